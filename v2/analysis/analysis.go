@@ -0,0 +1,82 @@
+/*
+Package analysis looks for patterns in Qonto transactions: anomalies such as duplicate
+charges or unusual amounts (DetectAnomalies), and subscriptions (DetectRecurring).
+
+The analyses work on a slice of transactions, or on an Iterator to read them from any source:
+
+	findings, err := analysis.DetectAnomaliesIterator(it, analysis.AnomalyOptions{})
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+*/
+package analysis
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// Iterator reads transactions one by one. Next returns io.EOF after the last transaction.
+type Iterator interface {
+	Next() (*qonto.Transaction, error)
+}
+
+// sliceIterator iterates over a slice.
+type sliceIterator struct {
+	transactions []*qonto.Transaction
+}
+
+// NewSliceIterator returns an Iterator over transactions.
+func NewSliceIterator(transactions []*qonto.Transaction) Iterator {
+	return &sliceIterator{transactions}
+}
+
+func (it *sliceIterator) Next() (*qonto.Transaction, error) {
+	if len(it.transactions) == 0 {
+		return nil, io.EOF
+	}
+	t := it.transactions[0]
+	it.transactions = it.transactions[1:]
+	return t, nil
+}
+
+// Collect reads all the transactions of it.
+func Collect(it Iterator) ([]*qonto.Transaction, error) {
+	var transactions []*qonto.Transaction
+	for {
+		t, err := it.Next()
+		if err == io.EOF {
+			return transactions, nil
+		}
+		if err != nil {
+			return transactions, err
+		}
+		transactions = append(transactions, t)
+	}
+}
+
+// VendorKey normalizes a label to group the transactions of a vendor: it keeps the lowercased
+// words, without digits and punctuation, so "NETFLIX.COM 12/03" and "Netflix.com 13/04" are
+// both "netflix com".
+func VendorKey(label string) string {
+	fields := strings.FieldsFunc(strings.ToLower(label), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// vendorKey returns the VendorKey of the label of t.
+func vendorKey(t *qonto.Transaction) string {
+	if t.Label == nil {
+		return ""
+	}
+	return VendorKey(*t.Label)
+}
+
+// isActive reports whether t was not declined or reversed.
+func isActive(t *qonto.Transaction) bool {
+	return t.Status != qonto.TransactionStatusDeclined && t.Status != qonto.TransactionStatusReversed
+}
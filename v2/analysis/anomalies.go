@@ -0,0 +1,243 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+// Default values for AnomalyOptions.
+const (
+	DefaultDuplicateWindow = 48 * time.Hour
+	DefaultMinHistory      = 5
+	DefaultMaxDeviation    = 3.5
+)
+
+// FindingKind is the kind of anomaly.
+type FindingKind string
+
+const (
+	// FindingDuplicate is a card debit with the same amount and vendor as a recent one
+	FindingDuplicate FindingKind = "duplicate"
+	// FindingUnusualAmount is a debit far from the usual amounts of the vendor
+	FindingUnusualAmount FindingKind = "unusual_amount"
+	// FindingUnexpectedCurrency is a transaction in an unexpected local currency
+	FindingUnexpectedCurrency FindingKind = "unexpected_currency"
+)
+
+// Finding is an anomaly found in a transaction.
+type Finding struct {
+	Kind        FindingKind
+	Transaction *qonto.Transaction
+	// Related lists the transactions explaining the finding (for eg. the original charge of a duplicate)
+	Related []*qonto.Transaction
+	// Reason explains the finding
+	Reason string
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Transaction.ID, f.Kind, f.Reason)
+}
+
+// AnomalyOptions configures DetectAnomalies.
+type AnomalyOptions struct {
+	// DuplicateWindow is the maximum delay between duplicate charges (defaults to DefaultDuplicateWindow)
+	DuplicateWindow time.Duration
+	// MinHistory is the minimum number of previous debits of a vendor to judge the amounts
+	// and currencies (defaults to DefaultMinHistory)
+	MinHistory int
+	// MaxDeviation is the maximum distance to the median amount of a vendor, in robust standard
+	// deviations (defaults to DefaultMaxDeviation)
+	MaxDeviation float64
+	// Currencies lists the expected local currencies. When empty, the currencies are expected
+	// to be the ones used in the history of the vendor.
+	Currencies []string
+}
+
+// DetectAnomalies looks for duplicate card debits, unusual debit amounts and unexpected currencies.
+// The currencies of all the transactions are checked against AnomalyOptions.Currencies, or else
+// against the history of their vendor on the same side (the transactions without label have no
+// history). Declined and reversed transactions are ignored. The findings are sorted by date.
+func DetectAnomalies(transactions []*qonto.Transaction, opt AnomalyOptions) []Finding {
+	if opt.DuplicateWindow <= 0 {
+		opt.DuplicateWindow = DefaultDuplicateWindow
+	}
+	if opt.MinHistory <= 0 {
+		opt.MinHistory = DefaultMinHistory
+	}
+	if opt.MaxDeviation <= 0 {
+		opt.MaxDeviation = DefaultMaxDeviation
+	}
+
+	// group the transactions by side and vendor, in chronological order
+	type group struct {
+		side    qonto.TransactionSide
+		history []*qonto.Transaction
+	}
+	var groups []*group
+	byVendor := make(map[string]*group)
+	var findings []Finding
+	for _, t := range transactions {
+		if !isActive(t) {
+			continue
+		}
+		vendor := vendorKey(t)
+		if vendor == "" {
+			if f := unexpectedCurrency(t, nil, &opt); f != nil {
+				findings = append(findings, *f)
+			}
+			continue
+		}
+		key := string(t.Side) + " " + vendor
+		g := byVendor[key]
+		if g == nil {
+			g = &group{side: t.Side}
+			byVendor[key] = g
+			groups = append(groups, g)
+		}
+		g.history = append(g.history, t)
+	}
+
+	for _, g := range groups {
+		history := g.history
+		sort.SliceStable(history, func(i, j int) bool { return history[i].EmittedAt.Before(history[j].EmittedAt) })
+		for i, t := range history {
+			if g.side == qonto.TransactionSideDebit {
+				if f := duplicate(t, history[:i], &opt); f != nil {
+					findings = append(findings, *f)
+				}
+				if f := unusualAmount(t, history[:i], &opt); f != nil {
+					findings = append(findings, *f)
+				}
+			}
+			if f := unexpectedCurrency(t, history[:i], &opt); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Transaction.EmittedAt.Before(findings[j].Transaction.EmittedAt)
+	})
+	return findings
+}
+
+// DetectAnomaliesIterator reads the transactions of it, and calls DetectAnomalies.
+func DetectAnomaliesIterator(it Iterator, opt AnomalyOptions) ([]Finding, error) {
+	transactions, err := Collect(it)
+	if err != nil {
+		return nil, err
+	}
+	return DetectAnomalies(transactions, opt), nil
+}
+
+// duplicate looks for a previous card debit of the same amount within the window.
+func duplicate(t *qonto.Transaction, previous []*qonto.Transaction, opt *AnomalyOptions) *Finding {
+	if t.OperationType != qonto.OperationTypeCard {
+		return nil
+	}
+	for i := len(previous) - 1; i >= 0; i-- {
+		p := previous[i]
+		delay := t.EmittedAt.Sub(p.EmittedAt)
+		if delay > opt.DuplicateWindow {
+			break
+		}
+		if p.OperationType == qonto.OperationTypeCard && p.AmountCents == t.AmountCents {
+			return &Finding{
+				Kind:        FindingDuplicate,
+				Transaction: t,
+				Related:     []*qonto.Transaction{p},
+				Reason:      fmt.Sprintf("same amount (%s) and vendor as %s, %s earlier", report.FormatCents(t.AmountCents), p.ID, delay),
+			}
+		}
+	}
+	return nil
+}
+
+// unusualAmount compares the amount of t with the median of the previous amounts, using
+// the median absolute deviation to ignore the outliers of the history.
+func unusualAmount(t *qonto.Transaction, previous []*qonto.Transaction, opt *AnomalyOptions) *Finding {
+	if len(previous) < opt.MinHistory {
+		return nil
+	}
+	amounts := make([]float64, len(previous))
+	for i, p := range previous {
+		amounts[i] = float64(p.AmountCents)
+	}
+	m := median(amounts)
+	deviations := make([]float64, len(amounts))
+	for i, a := range amounts {
+		deviations[i] = math.Abs(a - m)
+	}
+	// 1.4826 makes the MAD comparable to a standard deviation, and we tolerate 10% when all
+	// the previous amounts are equal
+	sigma := 1.4826 * median(deviations)
+	if sigma < 0.1*m/opt.MaxDeviation {
+		sigma = 0.1 * m / opt.MaxDeviation
+	}
+	if sigma == 0 {
+		return nil
+	}
+	distance := math.Abs(float64(t.AmountCents)-m) / sigma
+	if distance <= opt.MaxDeviation {
+		return nil
+	}
+	return &Finding{
+		Kind:        FindingUnusualAmount,
+		Transaction: t,
+		Related:     previous,
+		Reason: fmt.Sprintf("amount %s is %.1f deviations away from the usual %s (%d previous debits)",
+			report.FormatCents(t.AmountCents), distance, report.FormatCents(int64(m)), len(previous)),
+	}
+}
+
+// unexpectedCurrency checks the local currency of t against the expected currencies, or the
+// currencies of the previous transactions of the vendor.
+func unexpectedCurrency(t *qonto.Transaction, previous []*qonto.Transaction, opt *AnomalyOptions) *Finding {
+	currency := t.LocalCurrency
+	if currency == "" {
+		currency = t.Currency
+	}
+	if len(opt.Currencies) > 0 {
+		for _, c := range opt.Currencies {
+			if c == currency {
+				return nil
+			}
+		}
+		return &Finding{
+			Kind:        FindingUnexpectedCurrency,
+			Transaction: t,
+			Reason:      fmt.Sprintf("local currency %s is not one of %v", currency, opt.Currencies),
+		}
+	}
+	if len(previous) < opt.MinHistory {
+		return nil
+	}
+	for _, p := range previous {
+		if p.LocalCurrency == currency || (p.LocalCurrency == "" && p.Currency == currency) {
+			return nil
+		}
+	}
+	return &Finding{
+		Kind:        FindingUnexpectedCurrency,
+		Transaction: t,
+		Related:     previous,
+		Reason:      fmt.Sprintf("local currency %s was never used by this vendor (%d previous transactions)", currency, len(previous)),
+	}
+}
+
+// median returns the median of values, which is modified.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
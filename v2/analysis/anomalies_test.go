@@ -0,0 +1,88 @@
+package analysis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/analysis"
+)
+
+var start = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func card(id, label string, cents int64, emittedAt time.Time) *qonto.Transaction {
+	return &qonto.Transaction{
+		ID:            id,
+		Label:         &label,
+		AmountCents:   cents,
+		Currency:      "EUR",
+		LocalCurrency: "EUR",
+		Side:          qonto.TransactionSideDebit,
+		OperationType: qonto.OperationTypeCard,
+		Status:        qonto.TransactionStatusCompleted,
+		EmittedAt:     emittedAt,
+	}
+}
+
+func TestVendorKey(t *testing.T) {
+	if k := analysis.VendorKey("NETFLIX.COM 12/03"); k != "netflix com" {
+		t.Errorf("analysis.VendorKey() == %q; want %q", k, "netflix com")
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	var transactions []*qonto.Transaction
+	for i, cents := range []int64{2000, 2100, 1900, 2000, 2050} {
+		transactions = append(transactions, card(string(rune('a'+i)), "CAFE 42", cents, start.AddDate(0, 0, 7*i)))
+	}
+	declined := card("declined", "CAFE 42", 2000, start.AddDate(0, 1, 2))
+	declined.Status = qonto.TransactionStatusDeclined
+	usd := card("usd", "CAFE 43", 2000, start.AddDate(0, 2, 0))
+	usd.LocalCurrency = "USD"
+	transactions = append(transactions,
+		card("first", "CAFE 42", 2000, start.AddDate(0, 1, 1)),
+		declined,
+		card("double", "Cafe 42", 2000, start.AddDate(0, 1, 1).Add(time.Hour)),
+		card("big", "CAFE", 9000, start.AddDate(0, 1, 10)),
+		usd,
+	)
+
+	findings, err := analysis.DetectAnomaliesIterator(analysis.NewSliceIterator(transactions), analysis.AnomalyOptions{})
+	if err != nil {
+		t.Fatalf("analysis.DetectAnomaliesIterator() failed: %v", err)
+	}
+	want := []struct {
+		id   string
+		kind analysis.FindingKind
+	}{
+		{"double", analysis.FindingDuplicate},
+		{"big", analysis.FindingUnusualAmount},
+		{"usd", analysis.FindingUnexpectedCurrency},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("findings == %v; want %v", findings, want)
+	}
+	for i, w := range want {
+		if f := findings[i]; f.Transaction.ID != w.id || f.Kind != w.kind {
+			t.Errorf("findings[%d] == %s; want %s for %s", i, &f, w.kind, w.id)
+		}
+	}
+	if r := findings[0].Related; len(r) != 1 || r[0].ID != "first" {
+		t.Errorf("findings[0].Related == %v; want the first charge", r)
+	}
+}
+
+func TestDetectAnomalies_Currencies(t *testing.T) {
+	usd := card("usd", "SHOP", 2000, start)
+	usd.LocalCurrency = "USD"
+	refund := card("refund", "SHOP", 2000, start.AddDate(0, 0, 1))
+	refund.Side, refund.LocalCurrency = qonto.TransactionSideCredit, "GBP"
+	unlabelled := card("unlabelled", "", 2000, start.AddDate(0, 0, 2))
+	unlabelled.Label, unlabelled.LocalCurrency = nil, "CHF"
+	transactions := []*qonto.Transaction{usd, refund, unlabelled, card("eur", "SHOP", 2000, start.AddDate(0, 0, 5))}
+
+	findings := analysis.DetectAnomalies(transactions, analysis.AnomalyOptions{Currencies: []string{"EUR"}})
+	if len(findings) != 3 || findings[0].Transaction.ID != "usd" || findings[1].Transaction.ID != "refund" || findings[2].Transaction.ID != "unlabelled" {
+		t.Errorf("findings == %v; want usd, refund and unlabelled", findings)
+	}
+}
@@ -0,0 +1,353 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+// Default values for RecurringOptions.
+const (
+	DefaultMinOccurrences  = 3
+	DefaultAmountTolerance = 0.2
+)
+
+// Period is the interval between the charges of a subscription.
+type Period string
+
+const (
+	// PeriodWeekly is a charge every 7 days
+	PeriodWeekly Period = "weekly"
+	// PeriodMonthly is a charge every month
+	PeriodMonthly Period = "monthly"
+	// PeriodYearly is a charge every year
+	PeriodYearly Period = "yearly"
+)
+
+// periods lists the known periods, with their accepted intervals in days.
+var periods = []struct {
+	period   Period
+	min, max float64
+}{
+	{PeriodWeekly, 5, 9},
+	{PeriodMonthly, 26, 35},
+	{PeriodYearly, 350, 380},
+}
+
+// Next returns the date of the charge following t.
+func (p Period) Next(t time.Time) time.Time {
+	return p.Add(t, 1)
+}
+
+// Add returns the date n periods after t. Monthly and yearly dates are clamped to the end of
+// the month, so the charges of Jan 31 continue on Feb 29 (or 28), Mar 31, Apr 30 etc. when
+// computed from the same anchor t.
+func (p Period) Add(t time.Time, n int) time.Time {
+	switch p {
+	case PeriodWeekly:
+		return t.AddDate(0, 0, 7*n)
+	case PeriodMonthly:
+		return addMonths(t, n)
+	case PeriodYearly:
+		return addMonths(t, 12*n)
+	}
+	return t
+}
+
+// addMonths adds n months to t, without overflowing into the next month.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Count returns the number of periods from anchor to t, rounded to the closest charge date so
+// the charges a few days late or early are counted.
+func (p Period) Count(anchor, t time.Time) int {
+	n := 0
+	for distance(p.Add(anchor, n+1), t) < distance(p.Add(anchor, n), t) {
+		n++
+	}
+	return n
+}
+
+func distance(a, b time.Time) time.Duration {
+	if d := a.Sub(b); d > 0 {
+		return d
+	}
+	return b.Sub(a)
+}
+
+// Duration returns the approximate duration of the period.
+func (p Period) Duration() time.Duration {
+	return p.Next(time.Time{}).Sub(time.Time{})
+}
+
+// Subscription is a recurring debit.
+type Subscription struct {
+	// Vendor is the VendorKey of the labels
+	Vendor string
+	// Label is the label of the last charge
+	Label  string
+	Period Period
+	// Transactions lists the charges, oldest first
+	Transactions []*qonto.Transaction
+	// AmountCents is the amount of the last charge
+	AmountCents int64
+	// PreviousAmountCents is the amount before the last price change, or nil when the
+	// amount never changed. A price change needs at least two identical charges at the
+	// previous amount, so small variations of the amounts are not reported
+	PreviousAmountCents *int64
+	LastCharge          time.Time
+	// NextCharge and NextAmountCents predict the next charge, NextCharge is computed from
+	// the first charge so the end of month charges do not drift (see Charge)
+	NextCharge      time.Time
+	NextAmountCents int64
+	// Stopped is true when the next charge is overdue
+	Stopped bool
+}
+
+// Charge returns the date of the n-th charge following NextCharge (which is the charge 0),
+// computed from the first charge like NextCharge.
+func (s *Subscription) Charge(n int) time.Time {
+	if len(s.Transactions) == 0 {
+		return s.Period.Add(s.NextCharge, n)
+	}
+	anchor := s.Transactions[0].EmittedAt
+	return s.Period.Add(anchor, s.Period.Count(anchor, s.NextCharge)+n)
+}
+
+// PriceChanged reports whether the amount of the subscription changed.
+func (s *Subscription) PriceChanged() bool {
+	return s.PreviousAmountCents != nil
+}
+
+func (s *Subscription) String() string {
+	status := "active"
+	if s.Stopped {
+		status = "stopped"
+	}
+	if s.PriceChanged() {
+		status += fmt.Sprintf(", was %s", report.FormatCents(*s.PreviousAmountCents))
+	}
+	return fmt.Sprintf("%s %s %s, next on %s (%s)", s.Label, s.Period, report.FormatCents(s.AmountCents),
+		s.NextCharge.Format("2006-01-02"), status)
+}
+
+// RecurringOptions configures DetectRecurring.
+type RecurringOptions struct {
+	// Now is the date used to detect the stopped subscriptions (defaults to time.Now())
+	Now time.Time
+	// MinOccurrences is the minimum number of charges of a subscription (defaults to DefaultMinOccurrences)
+	MinOccurrences int
+	// AmountTolerance is the relative difference between the amounts of a cluster (defaults to DefaultAmountTolerance)
+	AmountTolerance float64
+}
+
+// DetectRecurring looks for subscriptions in the debit transactions.
+//
+// The debits are grouped by VendorKey, then clustered by amounts, and the clusters with
+// regular intervals become subscriptions. A cluster continuing a subscription at a different
+// price, one period after its last charge, is merged into it as a price change. A subscription
+// is stopped when its next charge is late by more than a third of the period.
+// The subscriptions are sorted by vendor.
+func DetectRecurring(transactions []*qonto.Transaction, opt RecurringOptions) []Subscription {
+	if opt.Now.IsZero() {
+		opt.Now = time.Now()
+	}
+	if opt.MinOccurrences <= 0 {
+		opt.MinOccurrences = DefaultMinOccurrences
+	}
+	if opt.AmountTolerance <= 0 {
+		opt.AmountTolerance = DefaultAmountTolerance
+	}
+
+	byVendor := make(map[string][]*qonto.Transaction)
+	for _, t := range transactions {
+		if !isActive(t) || t.Side != qonto.TransactionSideDebit {
+			continue
+		}
+		if key := vendorKey(t); key != "" {
+			byVendor[key] = append(byVendor[key], t)
+		}
+	}
+	vendors := make([]string, 0, len(byVendor))
+	for key := range byVendor {
+		vendors = append(vendors, key)
+	}
+	sort.Strings(vendors)
+
+	var res []Subscription
+	for _, key := range vendors {
+		for _, charges := range vendorSubscriptions(byVendor[key], &opt) {
+			res = append(res, newSubscription(key, charges.period, charges.transactions, opt.Now))
+		}
+	}
+	return res
+}
+
+// DetectRecurringIterator reads the transactions of it, and calls DetectRecurring.
+func DetectRecurringIterator(it Iterator, opt RecurringOptions) ([]Subscription, error) {
+	transactions, err := Collect(it)
+	if err != nil {
+		return nil, err
+	}
+	return DetectRecurring(transactions, opt), nil
+}
+
+// cluster is a group of charges of a vendor, oldest first.
+type cluster struct {
+	transactions []*qonto.Transaction
+	period       Period
+}
+
+func (c *cluster) first() time.Time { return c.transactions[0].EmittedAt }
+func (c *cluster) last() time.Time  { return c.transactions[len(c.transactions)-1].EmittedAt }
+
+// vendorSubscriptions returns the periodic clusters of the debits of a vendor.
+func vendorSubscriptions(debits []*qonto.Transaction, opt *RecurringOptions) []*cluster {
+	clusters := clusterAmounts(debits, opt.AmountTolerance)
+
+	var periodic, others []*cluster
+	for _, c := range clusters {
+		if len(c.transactions) >= opt.MinOccurrences {
+			if p, ok := inferPeriod(c.transactions); ok {
+				c.period = p
+				periodic = append(periodic, c)
+				continue
+			}
+		}
+		others = append(others, c)
+	}
+	sort.Slice(periodic, func(i, j int) bool { return periodic[i].first().Before(periodic[j].first()) })
+
+	// merge the price changes: clusters starting one period after the end of a subscription
+	var res []*cluster
+	merged := make(map[*cluster]bool)
+	for _, s := range periodic {
+		if merged[s] {
+			continue
+		}
+		for {
+			next := continuation(s, periodic, merged)
+			if next == nil {
+				next = continuation(s, others, merged)
+			}
+			if next == nil {
+				break
+			}
+			merged[next] = true
+			s.transactions = append(s.transactions, next.transactions...)
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// continuation returns the cluster among candidates continuing s, or nil.
+func continuation(s *cluster, candidates []*cluster, merged map[*cluster]bool) *cluster {
+	d := s.period.Duration()
+	for _, c := range candidates {
+		if c == s || merged[c] || (c.period != "" && c.period != s.period) {
+			continue
+		}
+		gap := c.first().Sub(s.last())
+		if gap < d/2 || gap > d*3/2 {
+			continue
+		}
+		if c.period == "" && len(c.transactions) > 1 {
+			if p, ok := inferPeriod(c.transactions); !ok || p != s.period {
+				continue
+			}
+		}
+		return c
+	}
+	return nil
+}
+
+// clusterAmounts groups the debits with close amounts, each cluster sorted by date.
+func clusterAmounts(debits []*qonto.Transaction, tolerance float64) []*cluster {
+	sorted := append([]*qonto.Transaction(nil), debits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].AmountCents < sorted[j].AmountCents })
+
+	var clusters []*cluster
+	var current *cluster
+	var base int64
+	for _, t := range sorted {
+		if current == nil || float64(t.AmountCents-base) > tolerance*float64(base) {
+			current = &cluster{}
+			clusters = append(clusters, current)
+			base = t.AmountCents
+		}
+		current.transactions = append(current.transactions, t)
+	}
+	for _, c := range clusters {
+		sort.SliceStable(c.transactions, func(i, j int) bool {
+			return c.transactions[i].EmittedAt.Before(c.transactions[j].EmittedAt)
+		})
+	}
+	return clusters
+}
+
+// inferPeriod looks for a known period in the median interval, and requires three quarters
+// of the intervals to match it.
+func inferPeriod(charges []*qonto.Transaction) (Period, bool) {
+	if len(charges) < 2 {
+		return "", false
+	}
+	intervals := make([]float64, len(charges)-1)
+	for i := 1; i < len(charges); i++ {
+		intervals[i-1] = charges[i].EmittedAt.Sub(charges[i-1].EmittedAt).Hours() / 24
+	}
+	m := median(append([]float64(nil), intervals...))
+	for _, p := range periods {
+		if m < p.min || m > p.max {
+			continue
+		}
+		regular := 0
+		for _, days := range intervals {
+			if days >= p.min && days <= p.max {
+				regular++
+			}
+		}
+		if float64(regular) >= math.Ceil(0.75*float64(len(intervals))) {
+			return p.period, true
+		}
+	}
+	return "", false
+}
+
+func newSubscription(vendor string, period Period, charges []*qonto.Transaction, now time.Time) Subscription {
+	first, last := charges[0], charges[len(charges)-1]
+	s := Subscription{
+		Vendor:          vendor,
+		Period:          period,
+		Transactions:    charges,
+		AmountCents:     last.AmountCents,
+		LastCharge:      last.EmittedAt,
+		NextCharge:      period.Add(first.EmittedAt, period.Count(first.EmittedAt, last.EmittedAt)+1),
+		NextAmountCents: last.AmountCents,
+	}
+	if last.Label != nil {
+		s.Label = *last.Label
+	}
+	// skip the charges at the current amount, then look for a stable run at another amount
+	i := len(charges) - 1
+	for i >= 0 && charges[i].AmountCents == last.AmountCents {
+		i--
+	}
+	if i >= 1 && charges[i-1].AmountCents == charges[i].AmountCents {
+		previous := charges[i].AmountCents
+		s.PreviousAmountCents = &previous
+	}
+	s.Stopped = now.Sub(s.NextCharge) > period.Duration()/3
+	return s
+}
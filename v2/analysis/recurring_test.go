@@ -0,0 +1,95 @@
+package analysis_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/analysis"
+)
+
+func TestDetectRecurring(t *testing.T) {
+	var transactions []*qonto.Transaction
+	add := func(label string, cents int64, at time.Time) {
+		transactions = append(transactions, card(label+at.Format("20060102"), label, cents, at))
+	}
+	now := start.AddDate(0, 6, 0)
+	for i := 0; i < 6; i++ {
+		// monthly subscription, price raised for the last two months
+		cents := int64(1299)
+		if i >= 4 {
+			cents = 1599
+		}
+		add("NETFLIX.COM", cents, start.AddDate(0, i, 1))
+		// stopped after 3 months
+		if i < 3 {
+			add("SLACK", 800, start.AddDate(0, i, 0))
+		}
+		// random purchases
+		add("AMAZON", int64(1000+i*1500), start.AddDate(0, 0, i*11))
+	}
+	for i := 0; i < 26; i++ {
+		add("WEEKLY MAG", 300, start.AddDate(0, 0, 7*i))
+	}
+	add("DOMAIN", 1500, start.AddDate(-2, 0, 0))
+	add("DOMAIN", 1500, start.AddDate(-1, 0, 2))
+	add("DOMAIN", 1500, start.AddDate(0, 0, -1))
+
+	subscriptions, err := analysis.DetectRecurringIterator(analysis.NewSliceIterator(transactions), analysis.RecurringOptions{Now: now})
+	if err != nil {
+		t.Fatalf("analysis.DetectRecurringIterator() failed: %v", err)
+	}
+	want := []string{
+		"DOMAIN yearly 15.00, next on 2021-01-01 (active)",
+		"NETFLIX.COM monthly 15.99, next on 2020-07-02 (active, was 12.99)",
+		"SLACK monthly 8.00, next on 2020-04-01 (stopped)",
+		"WEEKLY MAG weekly 3.00, next on 2020-07-01 (active)",
+	}
+	if len(subscriptions) != len(want) {
+		t.Fatalf("subscriptions == %v; want %v", subscriptions, want)
+	}
+	for i := range want {
+		if got := subscriptions[i].String(); got != want[i] {
+			t.Errorf("subscriptions[%d] == %s; want %s", i, got, want[i])
+		}
+	}
+	if n := len(subscriptions[1].Transactions); n != 6 {
+		t.Errorf("len(subscriptions[1].Transactions) == %d; want %d", n, 6)
+	}
+}
+
+func TestDetectRecurring_JitteryAmounts(t *testing.T) {
+	var transactions []*qonto.Transaction
+	for i, cents := range []int64{1000, 1001, 1000, 999, 1000} {
+		transactions = append(transactions, card(string(rune('a'+i)), "ELECTRICITY", cents, start.AddDate(0, i, 0)))
+	}
+	subscriptions := analysis.DetectRecurring(transactions, analysis.RecurringOptions{Now: start.AddDate(0, 5, 0)})
+	if len(subscriptions) != 1 {
+		t.Fatalf("subscriptions == %v; want 1 subscription", subscriptions)
+	}
+	if s := subscriptions[0]; s.PriceChanged() {
+		t.Errorf("subscriptions[0] == %s; want no price change", &s)
+	}
+}
+
+func TestDetectRecurring_EndOfMonth(t *testing.T) {
+	var transactions []*qonto.Transaction
+	for _, at := range []string{"2020-01-31", "2020-02-29", "2020-03-31", "2020-04-30"} {
+		date, _ := time.Parse("2006-01-02", at)
+		transactions = append(transactions, card(at, "HOSTING", 2000, date))
+	}
+	subscriptions := analysis.DetectRecurring(transactions, analysis.RecurringOptions{Now: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)})
+	if len(subscriptions) != 1 {
+		t.Fatalf("subscriptions == %v; want 1 subscription", subscriptions)
+	}
+	s := subscriptions[0]
+	// charged on the 31st, not on the 30th of the last charge
+	var got []string
+	for n := 0; n < 3; n++ {
+		got = append(got, s.Charge(n).Format("2006-01-02"))
+	}
+	if want := "2020-05-31 2020-06-30 2020-07-31"; strings.Join(got, " ") != want || !s.NextCharge.Equal(s.Charge(0)) {
+		t.Errorf("s.NextCharge == %s, charges == %v; want %s", s.NextCharge.Format("2006-01-02"), got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package qonto
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of entries of NewMemoryCache.
+const DefaultCacheSize = 1000
+
+// DefaultCacheTTLs are the lifetimes of the cached responses of the endpoints which rarely change.
+// The other endpoints, such as the transactions or the attachments (holding pre-signed URLs), are
+// not cached unless listed in Client.CacheTTLs. They apply to all the clients, and must not be
+// modified once requests are sent: see Client.CacheTTLs for the overrides of a client.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"/organizations/{id}": 5 * time.Minute,
+	"/labels":             10 * time.Minute,
+	"/memberships":        10 * time.Minute,
+}
+
+// CacheEntry is a cached API response.
+type CacheEntry struct {
+	Body    []byte
+	ETag    string // optional, used to revalidate the entry once expired
+	Expires time.Time
+}
+
+// Expired reports whether the entry must be revalidated (or fetched again) before use.
+func (e *CacheEntry) Expired() bool {
+	return time.Now().After(e.Expires)
+}
+
+// Cache stores the responses of GET requests. Keys start with the organization slug, the name of
+// the environment and the endpoint template, separated by spaces, for eg.
+// "acme-1234 production /labels https://...", so a single Cache can be shared by several clients.
+//
+// Get can return expired entries: the client revalidates them when they have an ETag.
+// Entries must not be modified once stored. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, e *CacheEntry)
+	Delete(key string)
+	// DeletePrefix deletes the entries whose key starts with prefix.
+	DeletePrefix(prefix string)
+}
+
+// CacheStats holds the counters of a LRUCache.
+type CacheStats struct {
+	Hits      uint64 // entries found, including the expired ones returned for revalidation
+	Misses    uint64
+	Evictions uint64 // entries dropped to make room for new ones
+	Entries   int
+}
+
+// LRUCache is an in-memory Cache holding a bounded number of entries: the least recently
+// used ones are evicted first.
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // of *lruItem, the most recently used first
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache returns an in-memory LRU Cache holding up to DefaultCacheSize entries.
+func NewMemoryCache() Cache {
+	return NewLRUCache(DefaultCacheSize)
+}
+
+// NewLRUCache returns an in-memory Cache holding up to size entries (defaults to DefaultCacheSize).
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &LRUCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the entry of key. Expired entries without an ETag are useless, and removed.
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	e := el.Value.(*lruItem).entry
+	if e.ETag == "" && e.Expired() {
+		c.remove(el)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	c.stats.Hits++
+	return e, true
+}
+
+// Set stores e, evicting the least recently used entry when the cache is full.
+func (c *LRUCache) Set(key string, e *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruItem).entry = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruItem{key: key, entry: e})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Delete removes the entry of key.
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+// DeletePrefix removes the entries whose key starts with prefix.
+func (c *LRUCache) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(el)
+		}
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *LRUCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.order.Len()
+	return s
+}
+
+// remove must be called with c.mu locked.
+func (c *LRUCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruItem).key)
+}
+
+// cacheKey returns the key of the response of u.
+func (c *Client) cacheKey(endpoint, u string) string {
+	return c.cachePrefix(endpoint) + " " + u
+}
+
+// cachePrefix returns the prefix of the keys of the responses of endpoint, or of all the
+// responses of the organization in its environment when endpoint is empty.
+func (c *Client) cachePrefix(endpoint string) string {
+	env := c.Environment.Name
+	if env == "" {
+		env = Production.Name
+	}
+	return c.Slug + " " + env + " " + endpoint
+}
+
+// cacheTTL returns the lifetime of the responses of endpoint, 0 when they must not be cached.
+func (c *Client) cacheTTL(endpoint string) time.Duration {
+	if ttl, ok := c.CacheTTLs[endpoint]; ok {
+		return ttl
+	}
+	return DefaultCacheTTLs[endpoint] // ⬅︎ 0 for the endpoints not listed
+}
+
+// InvalidateCache removes the cached responses of the organization for the given endpoints
+// (such as "/labels", or "/transactions" which also covers "/transactions/{id}"), or all of
+// them when no endpoint is given.
+//
+// The client already invalidates the responses of a resource after updating it.
+func (c *Client) InvalidateCache(endpoints ...string) {
+	if c.Cache == nil {
+		return
+	}
+	if len(endpoints) == 0 {
+		c.Cache.DeletePrefix(c.cachePrefix(""))
+		return
+	}
+	for _, endpoint := range endpoints {
+		c.Cache.DeletePrefix(c.cachePrefix(endpoint))
+	}
+}
+
+// invalidateResource removes the cached responses of the resource of endpoint after a
+// write: "/transactions/{id}/labels" invalidates all the "/transactions" endpoints.
+func (c *Client) invalidateResource(endpoint string) {
+	resource := strings.SplitN(strings.TrimPrefix(endpoint, "/"), "/", 2)[0]
+	c.InvalidateCache("/" + resource)
+}
@@ -0,0 +1,181 @@
+package qonto_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestCache(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/organizations/acme":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `{"organization":{"slug":"acme","bank_accounts":[]}}`)
+		case "/labels":
+			fmt.Fprint(w, `{"labels":[],"meta":{}}`)
+		default:
+			fmt.Fprint(w, `{"transactions":[],"transaction":{"transaction_id":"t1"},"meta":{}}`)
+		}
+	}))
+	defer srv.Close()
+
+	cache := qonto.NewLRUCache(10)
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Cache = cache
+	c.CacheTTLs = map[string]time.Duration{
+		"/organizations/{id}": 20 * time.Millisecond,
+		"/labels":             time.Hour,
+		"/memberships":        0,
+		"/transactions":       time.Hour,
+	}
+
+	// served from the cache, then revalidated with the ETag once expired
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrganization(); err != nil {
+			t.Fatalf("c.GetOrganization() failed: %v", err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	org, err := c.GetOrganization()
+	if err != nil || org.Slug != "acme" {
+		t.Fatalf("c.GetOrganization() == %v, %v after revalidation", org, err)
+	}
+	if n := requests["GET /organizations/acme"]; n != 2 {
+		t.Errorf("GET /organizations/acme sent %d times; want 2", n)
+	}
+
+	// writes invalidate the resource
+	for i := 0; i < 2; i++ {
+		if _, err = c.GetLabels(1, 10); err != nil {
+			t.Fatalf("c.GetLabels() failed: %v", err)
+		}
+		if _, err = c.GetTransactions("acme-account", "FR76", nil); err != nil {
+			t.Fatalf("c.GetTransactions() failed: %v", err)
+		}
+	}
+	note := "NOTE"
+	if _, err = c.UpdateTransaction("t1", &qonto.TransactionUpdate{Note: &note}); err != nil {
+		t.Fatalf("c.UpdateTransaction() failed: %v", err)
+	}
+	if _, err = c.GetTransactions("acme-account", "FR76", nil); err != nil {
+		t.Fatalf("c.GetTransactions() failed: %v", err)
+	}
+	if n := requests["GET /transactions"]; n != 2 {
+		t.Errorf("GET /transactions sent %d times; want 2", n)
+	}
+
+	// explicit invalidation
+	c.InvalidateCache("/labels")
+	if _, err = c.GetLabels(1, 10); err != nil {
+		t.Fatalf("c.GetLabels() failed: %v", err)
+	}
+	if n := requests["GET /labels"]; n != 2 {
+		t.Errorf("GET /labels sent %d times; want 2", n)
+	}
+	c.InvalidateCache()
+	if s := cache.Stats(); s.Entries != 0 || s.Hits == 0 {
+		t.Errorf("cache.Stats() == %+v; want no entries", s)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := qonto.NewLRUCache(2)
+	expires := time.Now().Add(time.Hour)
+	cache.Set("a", &qonto.CacheEntry{Body: []byte("a"), Expires: expires})
+	cache.Set("b", &qonto.CacheEntry{Body: []byte("b"), Expires: expires})
+	cache.Get("a")
+	cache.Set("c", &qonto.CacheEntry{Body: []byte("c"), Expires: expires}) // ⬅︎ evicts b
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	cache.Set("d", &qonto.CacheEntry{Body: []byte("d"), Expires: time.Now().Add(-time.Second)})
+	if _, ok := cache.Get("d"); ok {
+		t.Error("expired d without ETag was returned")
+	}
+	if _, ok := cache.Get("c"); !ok {
+		t.Error("c was evicted")
+	}
+	want := qonto.CacheStats{Hits: 2, Misses: 2, Evictions: 2, Entries: 1}
+	if s := cache.Stats(); s != want {
+		t.Errorf("cache.Stats() == %+v; want %+v", s, want)
+	}
+}
+
+func TestCache_Isolation(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"labels":[],"meta":{}}`)
+	}))
+	defer srv.Close()
+
+	// the same URL in two environments is cached twice
+	cache := qonto.NewMemoryCache()
+	for _, env := range []string{"production", "sandbox", "sandbox"} {
+		c := qonto.NewClient("acme", "secret", nil)
+		c.Environment = qonto.CustomEnvironment(env, srv.URL, nil)
+		c.Cache = cache
+		if _, err := c.GetLabels(1, 10); err != nil {
+			t.Fatalf("c.GetLabels() failed: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("requests == %d; want 2", n)
+	}
+
+	// the overrides of a client are its own
+	want := qonto.DefaultCacheTTLs["/labels"]
+	r := qonto.NewRegistry(nil)
+	r.CacheTTLs = map[string]time.Duration{"/labels": time.Second}
+	if err := r.Add(qonto.Credentials{Name: "acme", Slug: "acme", SecretKey: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := r.Client("acme")
+	c.CacheTTLs["/labels"] = 0
+	if got := qonto.DefaultCacheTTLs["/labels"]; got != want {
+		t.Errorf("qonto.DefaultCacheTTLs[/labels] == %v; want %v", got, want)
+	}
+	if got := r.CacheTTLs["/labels"]; got != time.Second {
+		t.Errorf("r.CacheTTLs[/labels] == %v; want %v", got, time.Second)
+	}
+}
+
+func TestCache_Transactions(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"transactions":[],"meta":{}}`)
+	}))
+	defer srv.Close()
+
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Cache = qonto.NewMemoryCache()
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetTransactionsContext(context.Background(), "acme-account", "FR76", nil); err != nil {
+			t.Fatalf("c.GetTransactionsContext() failed: %v", err)
+		}
+	}
+	// the transactions are not cached by default
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("requests == %d; want 3", n)
+	}
+}
@@ -1,19 +1,25 @@
 package qonto
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 )
 
-// BaseURL is the root URL for all API calls
+// BaseURL is the root URL for all API calls in the Production environment
 var BaseURL = "https://thirdparty.qonto.eu/v2"
 
 // ErrMissingBankAccountSlug error
@@ -47,20 +53,44 @@ func (q APIError) Error() string {
 
 // Client allows to send requests to the Qonto API servers.
 type Client struct {
-	h         *http.Client
-	Slug      string // the organization slug.
-	SecretKey string // the secret key, associated to the organization.
+	h           *http.Client
+	credentials CredentialsProvider
+	oauth       *OAuthSource
+	Slug        string // the organization slug.
+
+	Environment Environment  // the API environment (defaults to Production).
+	Middlewares []Middleware // optional, wrap the HTTP requests (the first one is the outermost).
+	Logger      *slog.Logger // optional, logs the HTTP requests without their credentials (successes at the Debug level).
+	Observer    Observer     // optional, receives the start and end of the API requests, for metrics and tracing.
+
+	Limiter *RateLimiter // optional, spaces out the API requests (can be shared between clients).
+	Cache   Cache        // optional, caches the GET responses of the endpoints with a TTL (can be shared between clients).
+	// CacheTTLs overrides DefaultCacheTTLs by endpoint template, a zero duration disables the
+	// cache of the endpoint (optional). The endpoints listed in neither are not cached.
+	CacheTTLs map[string]time.Duration
+
+	// DisableSingleFlight sends every GET request, instead of sharing the response of an
+	// identical request in progress between the concurrent callers.
+	DisableSingleFlight bool
+
+	flightsMu sync.Mutex
+	flights   map[string]*flight
 }
 
 // NewClient creates and initialisez a new Client with the provided credentials.
 func NewClient(slug, secretKey string, httpClient *http.Client) *Client {
+	return NewClientWithCredentials(slug, StaticCredentials(secretKey), httpClient)
+}
+
+// NewClientWithCredentials creates a new Client, asking the secret key to credentials before each request.
+func NewClientWithCredentials(slug string, credentials CredentialsProvider, httpClient *http.Client) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 	return &Client{
-		h:         httpClient,
-		Slug:      slug,
-		SecretKey: secretKey,
+		h:           httpClient,
+		credentials: credentials,
+		Slug:        slug,
 	}
 }
 
@@ -75,7 +105,7 @@ func (c *Client) GetOrganization() (*Organization, error) {
 
 // GetOrganizationContext fetches the organization details, attaching ctx to the request.
 func (c *Client) GetOrganizationContext(ctx context.Context) (*Organization, error) {
-	path := fmt.Sprintf("%s/organizations/%s", BaseURL, c.Slug)
+	path := fmt.Sprintf("%s/organizations/%s", c.baseURL(), c.Slug)
 
 	// this endpoint responds with a JSON object holding an "organization" key
 	var response struct {
@@ -119,9 +149,9 @@ func (c *Client) GetLabels(currentPage, perPage int) (page *LabelsPage, err erro
 
 // GetLabelsContext fetches the list of labels defined in the current Organization
 func (c *Client) GetLabelsContext(ctx context.Context, currentPage, perPage int) (page *LabelsPage, err error) {
-	u, err := addPaginationQueryParams(BaseURL+"/labels", currentPage, perPage)
+	u, err := addPaginationQueryParams(c.baseURL()+"/labels", currentPage, perPage)
 	if err != nil {
-		return nil, err // ⬅︎ should not happen unless BaseURL is modified
+		return nil, err // ⬅︎ should not happen unless the base URL is modified
 	}
 	err = c.getJSON(ctx, u, &page)
 	return
@@ -163,9 +193,9 @@ func (c *Client) GetMemberships(currentPage, perPage int) (page *MembershipsPage
 
 // GetMembershipsContext fetches the list of members of the current Organization
 func (c *Client) GetMembershipsContext(ctx context.Context, currentPage, perPage int) (page *MembershipsPage, err error) {
-	u, err := addPaginationQueryParams(BaseURL+"/memberships", currentPage, perPage)
+	u, err := addPaginationQueryParams(c.baseURL()+"/memberships", currentPage, perPage)
 	if err != nil {
-		return nil, err // ⬅︎ should not happen unless BaseURL is modified
+		return nil, err // ⬅︎ should not happen unless the base URL is modified
 	}
 	err = c.getJSON(ctx, u, &page)
 	return
@@ -235,9 +265,9 @@ func (c *Client) GetTransactionsForAccountContext(ctx context.Context, ba *BankA
 
 // GetTransactionsContext fetches the list of transactions of the given bank account
 func (c *Client) GetTransactionsContext(ctx context.Context, bankAccountID, IBAN string, options *GetTransactionOptions) (page *TransactionsPage, err error) {
-	u, err := getTransactionsURL(bankAccountID, IBAN, options)
+	u, err := getTransactionsURL(c.baseURL(), bankAccountID, IBAN, options)
 	if err != nil {
-		return nil, err // ⬅︎ should not happen unless BaseURL is modified
+		return nil, err // ⬅︎ should not happen unless the base URL is modified
 	}
 	err = c.getJSON(ctx, u, &page)
 	return
@@ -295,6 +325,62 @@ func (c *Client) GetAllTransactionsContext(ctx context.Context, bankAccountID, I
 	return
 }
 
+// TransactionUpdate lists the changes to apply to a transaction with UpdateTransaction*.
+// Nil fields are left untouched.
+type TransactionUpdate struct {
+	Note           *string  `json:"note,omitempty"`
+	VATRate        *float64 `json:"vat_rate,omitempty"`
+	VATAmountCents *int64   `json:"vat_amount_cents,omitempty"`
+}
+
+// UpdateTransaction changes the note or VAT details of a transaction, and returns the updated transaction
+func (c *Client) UpdateTransaction(id string, update *TransactionUpdate) (*Transaction, error) {
+	return c.UpdateTransactionContext(context.Background(), id, update)
+}
+
+// UpdateTransactionContext changes the note or VAT details of a transaction, and returns the updated transaction
+func (c *Client) UpdateTransactionContext(ctx context.Context, id string, update *TransactionUpdate) (*Transaction, error) {
+	if update == nil {
+		update = &TransactionUpdate{}
+	}
+	u := fmt.Sprintf("%s/transactions/%s", c.baseURL(), url.PathEscape(id))
+
+	request := struct {
+		Transaction *TransactionUpdate `json:"transaction"`
+	}{update}
+	var response struct {
+		Transaction *Transaction `json:"transaction"`
+	}
+	if err := c.doJSON(ctx, http.MethodPatch, u, &request, &response); err != nil {
+		return nil, err
+	}
+	return response.Transaction, nil
+}
+
+// UpdateTransactionLabels replaces the labels of a transaction, and returns the updated transaction
+func (c *Client) UpdateTransactionLabels(id string, labelIDs []string) (*Transaction, error) {
+	return c.UpdateTransactionLabelsContext(context.Background(), id, labelIDs)
+}
+
+// UpdateTransactionLabelsContext replaces the labels of a transaction, and returns the updated transaction
+func (c *Client) UpdateTransactionLabelsContext(ctx context.Context, id string, labelIDs []string) (*Transaction, error) {
+	u := fmt.Sprintf("%s/transactions/%s/labels", c.baseURL(), url.PathEscape(id))
+	if labelIDs == nil {
+		labelIDs = []string{} // ⬅︎ "null" would not clear the labels
+	}
+
+	request := struct {
+		LabelIDs []string `json:"label_ids"`
+	}{labelIDs}
+	var response struct {
+		Transaction *Transaction `json:"transaction"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, u, &request, &response); err != nil {
+		return nil, err
+	}
+	return response.Transaction, nil
+}
+
 // GetAttachment downloads a remote attachment given it's id
 func (c *Client) GetAttachment(id string) (attachment *Attachment, err error) {
 	return c.GetAttachmentContext(context.Background(), id)
@@ -302,7 +388,7 @@ func (c *Client) GetAttachment(id string) (attachment *Attachment, err error) {
 
 // GetAttachmentContext downloads a remote attachment given it's id
 func (c *Client) GetAttachmentContext(ctx context.Context, id string) (*Attachment, error) {
-	u := fmt.Sprintf("%s/attachments/%s", BaseURL, id)
+	u := fmt.Sprintf("%s/attachments/%s", c.baseURL(), id)
 
 	var response struct {
 		Attachment *Attachment `json:"attachment"`
@@ -314,6 +400,37 @@ func (c *Client) GetAttachmentContext(ctx context.Context, id string) (*Attachme
 	return response.Attachment, nil
 }
 
+// UploadAttachment attaches a file to a transaction
+func (c *Client) UploadAttachment(transactionID, filename string, content io.Reader) error {
+	return c.UploadAttachmentContext(context.Background(), transactionID, filename, content)
+}
+
+// UploadAttachmentContext attaches a file to a transaction
+func (c *Client) UploadAttachmentContext(ctx context.Context, transactionID, filename string, content io.Reader) error {
+	u := fmt.Sprintf("%s/transactions/%s/attachments", c.baseURL(), url.PathEscape(transactionID))
+
+	// the file is sent as a multipart form
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, content); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, &body)
+	if err != nil {
+		return err // ⬅︎ should not happen unless we override the base URL
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.do(req, nil)
+}
+
 // DownloadAttachment downloads the file contents of an attachment
 func (c *Client) DownloadAttachment(a *Attachment) ([]byte, error) {
 	return c.DownloadAttachmentContext(context.Background(), a)
@@ -328,12 +445,27 @@ func (c *Client) DownloadAttachmentContext(ctx context.Context, a *Attachment) (
 		return nil, ErrMissingAttachmentURL
 	}
 	req, err := http.NewRequestWithContext(ctx, "GET", a.URL, nil)
-	res, err := c.h.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	var info *RequestInfo
+	result := &RequestResult{}
+	if c.Observer != nil {
+		info = &RequestInfo{Method: req.Method, Endpoint: EndpointAttachmentFile, Organization: c.Slug}
+		req = req.WithContext(c.Observer.RequestStarted(ctx, info))
+		defer func() { c.Observer.RequestFinished(req.Context(), info, result) }()
+	}
+
+	start := time.Now()
+	res, err := c.doer().Do(req)
+	c.logRequest(req, res, err, time.Since(start), 1, true)
+	if err != nil {
+		result.Duration, result.Err = time.Since(start), err
+		return nil, err
+	}
 
 	data, err := ioutil.ReadAll(res.Body)
+	result.StatusCode, result.Bytes, result.Duration, result.Err = res.StatusCode, int64(len(data)), time.Since(start), err
 	if err != nil {
 		_ = res.Body.Close()
 		return nil, err
@@ -362,7 +494,7 @@ func (c *Client) DownloadAttachmentToFileContext(ctx context.Context, id, filena
 func addPaginationQueryParams(baseURL string, currentPage, perPage int) (string, error) {
 	u, err := url.Parse(baseURL)
 	if err != nil {
-		return baseURL, err // ⬅︎ should not happen unless BaseURL is modified
+		return baseURL, err // ⬅︎ should not happen unless the base URL is modified
 	}
 
 	// encode the options in a query params
@@ -373,15 +505,15 @@ func addPaginationQueryParams(baseURL string, currentPage, perPage int) (string,
 	if perPage > 0 {
 		query.Set("per_page", strconv.Itoa(perPage))
 	}
-	u.RawQuery = u.Query().Encode()
+	u.RawQuery = query.Encode()
 
 	return u.String(), nil
 }
 
-func getTransactionsURL(slug, IBAN string, options *GetTransactionOptions) (string, error) {
-	u, err := url.Parse(BaseURL + "/transactions")
+func getTransactionsURL(baseURL, slug, IBAN string, options *GetTransactionOptions) (string, error) {
+	u, err := url.Parse(baseURL + "/transactions")
 	if err != nil {
-		return "", err // ⬅︎ should not happen unless BaseURL is modified
+		return "", err // ⬅︎ should not happen unless the base URL is modified
 	}
 
 	// from here we append lots of query params
@@ -431,14 +563,100 @@ func getTransactionsURL(slug, IBAN string, options *GetTransactionOptions) (stri
 }
 
 func (c *Client) getJSON(ctx context.Context, u string, ref interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	return c.doJSON(ctx, http.MethodGet, u, nil, ref)
+}
+
+// doJSON sends a request to the API, with body encoded as JSON (when not nil), and decodes the response into ref.
+func (c *Client) doJSON(ctx context.Context, method, u string, body interface{}, ref interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
 	if err != nil {
-		return err // ⬅︎ should not happen unless we override BaseURL
+		return err // ⬅︎ should not happen unless we override the base URL
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.do(req, ref)
+}
+
+// do sends an authenticated request to the API, and decodes the JSON response into ref (when not nil).
+func (c *Client) do(req *http.Request, ref interface{}) error {
+	if req.Method == http.MethodGet && ref != nil && !c.DisableSingleFlight {
+		return c.shared(req, ref)
+	}
+	return c.observe(req, ref)
+}
+
+// observe implements do, notifying the Observer.
+func (c *Client) observe(req *http.Request, ref interface{}) error {
+	if c.Observer == nil {
+		return c.doRequest(req, ref, nil)
+	}
+	info := &RequestInfo{Method: req.Method, Endpoint: c.endpoint(req.URL), Organization: c.Slug}
+	ctx := c.Observer.RequestStarted(req.Context(), info)
+	result := &RequestResult{}
+	start := time.Now()
+	result.Err = c.doRequest(req.WithContext(ctx), ref, result)
+	result.Duration = time.Since(start)
+	c.Observer.RequestFinished(ctx, info, result)
+	return result.Err
+}
+
+// doRequest implements do, filling result when not nil.
+func (c *Client) doRequest(req *http.Request, ref interface{}, result *RequestResult) error {
+	// GET responses can be served from the cache, or revalidated with their ETag
+	var cached *CacheEntry
+	cacheKey, endpoint := "", ""
+	if c.Cache != nil {
+		endpoint = c.endpoint(req.URL)
+	}
+	if c.Cache != nil && req.Method == http.MethodGet && ref != nil && c.cacheTTL(endpoint) > 0 {
+		cacheKey = c.cacheKey(endpoint, req.URL.String())
+		if e, ok := c.Cache.Get(cacheKey); ok {
+			if !e.Expired() {
+				if result != nil {
+					result.CacheHit = true
+					result.StatusCode = http.StatusOK
+				}
+				return decodeResponse(e.Body, ref)
+			}
+			if e.ETag != "" {
+				cached = e
+				req.Header.Set("If-None-Match", e.ETag)
+			}
+		}
+	}
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(req.Context()); err != nil {
+			return err
+		}
+	}
+
+	for k, values := range c.Environment.Header {
+		req.Header[k] = append([]string(nil), values...)
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("%s:%s", c.Slug, c.SecretKey))
-	res, err := c.h.Do(req)
+	res, err := c.send(req, result)
 	if err != nil {
-		return fmt.Errorf("Qonto API could not be reached: %w", err)
+		return err
+	}
+	if result != nil {
+		result.StatusCode = res.StatusCode
+		res.Body = &countingBody{ReadCloser: res.Body, n: &result.Bytes}
+	}
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		_ = res.Body.Close()
+		if result != nil {
+			result.Revalidated = true
+		}
+		c.Cache.Set(cacheKey, &CacheEntry{Body: cached.Body, ETag: cached.ETag, Expires: time.Now().Add(c.cacheTTL(endpoint))})
+		return decodeResponse(cached.Body, ref)
 	}
 	if res.StatusCode > 299 {
 		ae := APIError{
@@ -451,7 +669,25 @@ func (c *Client) getJSON(ctx context.Context, u string, ref interface{}) error {
 			return ae // ⬅︎ APIError will retain the description sent by Qonto
 		}
 		// could not decode the JSON body, we send a generic error
-		return fmt.Errorf("GET %s returned %d", u, res.StatusCode)
+		return fmt.Errorf("%s %s returned %d", req.Method, req.URL.String(), res.StatusCode)
+	}
+	if c.Cache != nil && req.Method != http.MethodGet {
+		c.invalidateResource(endpoint)
+	}
+	if ref == nil {
+		return res.Body.Close()
+	}
+	if cacheKey != "" {
+		body, err := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Could not read the response from Qonto API: %w", err)
+		}
+		if err = decodeResponse(body, ref); err != nil {
+			return err
+		}
+		c.Cache.Set(cacheKey, &CacheEntry{Body: body, ETag: res.Header.Get("ETag"), Expires: time.Now().Add(c.cacheTTL(endpoint))})
+		return nil
 	}
 	err = json.NewDecoder(res.Body).Decode(ref)
 	if err != nil {
@@ -460,3 +696,62 @@ func (c *Client) getJSON(ctx context.Context, u string, ref interface{}) error {
 	}
 	return res.Body.Close()
 }
+
+// send authenticates and sends req. With OAuth, a rejected access token is refreshed, and
+// the request is sent again once (counted in result.Retries, when result is not nil).
+func (c *Client) send(req *http.Request, result *RequestResult) (*http.Response, error) {
+	if c.oauth == nil {
+		secretKey, err := c.credentials.SecretKey(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("Could not get the credentials: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("%s:%s", c.Slug, secretKey))
+		return c.roundTrip(req, 1)
+	}
+
+	t, err := c.oauth.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("Could not get the OAuth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+	res, err := c.roundTrip(req, 1)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return res, err
+	}
+
+	// the token was revoked or expired early: we refresh it and try again
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	_ = res.Body.Close()
+	if t, err = c.oauth.Refresh(req.Context(), t); err != nil {
+		return nil, fmt.Errorf("Could not refresh the OAuth token: %w", err)
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+t.AccessToken)
+	if result != nil {
+		result.Retries++
+	}
+	return c.roundTrip(retry, 2)
+}
+
+// roundTrip sends an API request, attempt being 1 for the first try.
+func (c *Client) roundTrip(req *http.Request, attempt int) (*http.Response, error) {
+	start := time.Now()
+	res, err := c.doer().Do(req)
+	c.logRequest(req, res, err, time.Since(start), attempt, false)
+	if err != nil {
+		return nil, fmt.Errorf("Qonto API could not be reached: %w", err)
+	}
+	return res, nil
+}
+
+func decodeResponse(body []byte, ref interface{}) error {
+	if err := json.Unmarshal(body, ref); err != nil {
+		return fmt.Errorf("Could not decode the response from Qonto API: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"mime"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -222,3 +224,65 @@ func findTransactionWithAttachment(t *testing.T, transactions []*qonto.Transacti
 	}
 	return nil
 }
+
+func TestUpdateTransaction(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, strings.TrimSpace(string(body))))
+		fmt.Fprint(w, `{"transaction":{"transaction_id":"t1"}}`)
+	}))
+	defer srv.Close()
+
+	c := qonto.NewClient("slug", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	note := "NOTE"
+	if _, err := c.UpdateTransaction("t1", &qonto.TransactionUpdate{Note: &note}); err != nil {
+		t.Fatalf("c.UpdateTransaction() failed: %v", err)
+	}
+	tr, err := c.UpdateTransactionLabels("t1", nil)
+	if err != nil {
+		t.Fatalf("c.UpdateTransactionLabels() failed: %v", err)
+	}
+	if tr.ID != "t1" {
+		t.Errorf("tr.ID == %q; want %q", tr.ID, "t1")
+	}
+
+	want := []string{
+		`PATCH /transactions/t1 {"transaction":{"note":"NOTE"}}`,
+		`PUT /transactions/t1/labels {"label_ids":[]}`,
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("requests == %v; want %v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("requests[%d] == %s; want %s", i, requests[i], want[i])
+		}
+	}
+}
+
+func TestUploadAttachment(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("r.FormFile() failed: %v", err)
+			return
+		}
+		defer f.Close()
+		body, _ := ioutil.ReadAll(f)
+		got = fmt.Sprintf("%s %s %s %s", r.Method, r.URL.Path, h.Filename, body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	c := qonto.NewClient("slug", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	if err := c.UploadAttachment("t1", "receipts/receipt.pdf", strings.NewReader("PDF")); err != nil {
+		t.Fatalf("c.UploadAttachment() failed: %v", err)
+	}
+	if want := "POST /transactions/t1/attachments receipt.pdf PDF"; got != want {
+		t.Errorf("request == %q; want %q", got, want)
+	}
+}
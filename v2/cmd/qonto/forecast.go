@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/forecast"
+)
+
+func runForecast(ctx context.Context, c *qonto.Client, args []string) error {
+	opt := forecast.Options{
+		Start:    time.Now(),
+		Location: time.Local,
+	}
+
+	fs := flag.NewFlagSet("forecast", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table, csv or json")
+	schedule := fs.String("schedule", "", "CSV file of scheduled items (date,label,amount,repeat)")
+	low := fs.Float64("low", 0, "alert when the balance goes below this amount")
+	history := fs.Int("history", 13, "months of history used to detect the recurring payments")
+	fs.IntVar(&opt.Horizon, "days", forecast.DefaultHorizon, "number of projected days")
+	_ = fs.Parse(args)
+	opt.LowBalanceCents = int64(math.Round(*low * 100))
+
+	if *schedule != "" {
+		f, err := os.Open(*schedule)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if opt.Scheduled, err = forecast.LoadSchedule(f, time.Local); err != nil {
+			return err
+		}
+	}
+
+	ba, err := c.GetBankAccountContext(ctx)
+	if err != nil {
+		return err
+	}
+	from := opt.Start.AddDate(0, -*history, 0)
+	transactions, err := c.GetAllTransactionsForAccountContext(ctx, ba, &qonto.GetTransactionOptions{
+		SettledAtFrom: &from,
+	})
+	if err != nil {
+		return err
+	}
+	// the pending charges are not settled yet, but they are already deducted from the
+	// authorized balance: without them, their subscriptions would be charged again
+	pending, err := c.GetAllTransactionsForAccountContext(ctx, ba, &qonto.GetTransactionOptions{
+		Statuses: []qonto.TransactionStatus{qonto.TransactionStatusPending},
+	})
+	if err != nil {
+		return err
+	}
+	transactions = append(transactions, pending...)
+
+	p, err := forecast.Forecast(ba, transactions, opt)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "table":
+		return p.WriteTable(os.Stdout)
+	case "csv":
+		return p.WriteCSV(os.Stdout)
+	case "json":
+		return p.WriteJSON(os.Stdout)
+	default:
+		return fmt.Errorf("Unknown format %q", *format)
+	}
+}
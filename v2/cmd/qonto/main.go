@@ -0,0 +1,72 @@
+// Command qonto prints reports and forecasts about a Qonto account.
+//
+// The credentials are read from the QONTO_SLUG and QONTO_SECRET_KEY environment variables.
+//
+// Usage:
+//
+//	qonto report [-by month|label|operation_type|side|member] [-from 2006-01-02] [-to 2006-01-02] [-compare] [-format table|csv|json]
+//	qonto vat [-from 2006-01-02] [-to 2006-01-02] [-format table|csv|json] [-lines]
+//	qonto forecast [-days 90] [-schedule items.csv] [-low 1000.00] [-history 13] [-format table|csv|json]
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+const dateFormat = "2006-01-02"
+
+var commands = map[string]func(ctx context.Context, c *qonto.Client, args []string) error{
+	"report":   runReport,
+	"vat":      runVAT,
+	"forecast": runForecast,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: qonto COMMAND [OPTIONS]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  report    cash-flow report")
+		fmt.Fprintln(os.Stderr, "  vat       VAT summary")
+		fmt.Fprintln(os.Stderr, "  forecast  projected balance")
+		os.Exit(2)
+	}
+
+	slug, secretKey := os.Getenv("QONTO_SLUG"), os.Getenv("QONTO_SECRET_KEY")
+	if slug == "" || secretKey == "" {
+		fmt.Fprintln(os.Stderr, "QONTO_SLUG and QONTO_SECRET_KEY must be set")
+		os.Exit(2)
+	}
+	c := qonto.NewClient(slug, secretKey, nil)
+
+	if err := commands[os.Args[1]](context.Background(), c, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// dateFlag is a flag.Value parsing dates as "2006-01-02".
+type dateFlag struct {
+	t *time.Time
+}
+
+func (d dateFlag) String() string {
+	if d.t == nil || d.t.IsZero() {
+		return ""
+	}
+	return d.t.Format(dateFormat)
+}
+
+func (d dateFlag) Set(s string) error {
+	t, err := time.ParseInLocation(dateFormat, s, time.Local)
+	if err != nil {
+		return err
+	}
+	*d.t = t
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+func runReport(ctx context.Context, c *qonto.Client, args []string) error {
+	now := time.Now()
+	opt := report.CashFlowOptions{
+		From:     time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local),
+		Location: time.Local,
+	}
+	opt.To = opt.From.AddDate(0, 1, 0)
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	by := fs.String("by", string(report.ByMonth), "grouping: month, label, operation_type, side or member")
+	format := fs.String("format", "table", "output format: table, csv or json")
+	fs.Var(dateFlag{&opt.From}, "from", "start of the period (defaults to the start of the month)")
+	fs.Var(dateFlag{&opt.To}, "to", "end of the period, excluded (defaults to the end of the month)")
+	fs.BoolVar(&opt.Compare, "compare", false, "compare with the previous period")
+	_ = fs.Parse(args)
+	opt.GroupBy = report.Dimension(*by)
+
+	ba, err := c.GetBankAccountContext(ctx)
+	if err != nil {
+		return err
+	}
+	// we need the previous period too when comparing
+	settledFrom := opt.From
+	if opt.Compare {
+		settledFrom = opt.From.Add(-opt.To.Sub(opt.From))
+	}
+	transactions, err := c.GetAllTransactionsForAccountContext(ctx, ba, &qonto.GetTransactionOptions{
+		SettledAtFrom: &settledFrom,
+		SettledAtTo:   &opt.To,
+	})
+	if err != nil {
+		return err
+	}
+	switch opt.GroupBy {
+	case report.ByLabel:
+		if opt.Labels, err = c.GetAllLabelsContext(ctx, 0, 0); err != nil {
+			return err
+		}
+	case report.ByMember:
+		if opt.Memberships, err = c.GetAllMembershipsContext(ctx, 0, 0); err != nil {
+			return err
+		}
+	}
+
+	r, err := report.CashFlowReport(transactions, opt)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "table":
+		return r.WriteTable(os.Stdout)
+	case "csv":
+		return r.WriteCSV(os.Stdout)
+	case "json":
+		return r.WriteJSON(os.Stdout)
+	default:
+		return fmt.Errorf("Unknown format %q", *format)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+func runVAT(ctx context.Context, c *qonto.Client, args []string) error {
+	// defaults to the previous month
+	now := time.Now()
+	opt := report.VATOptions{
+		To: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local),
+	}
+	opt.From = opt.To.AddDate(0, -1, 0)
+
+	fs := flag.NewFlagSet("vat", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table, csv or json")
+	lines := fs.Bool("lines", false, "write the detail of the transactions instead of the totals (csv only)")
+	fs.Var(dateFlag{&opt.From}, "from", "start of the period (defaults to the start of the previous month)")
+	fs.Var(dateFlag{&opt.To}, "to", "end of the period, excluded (defaults to the start of the month)")
+	_ = fs.Parse(args)
+
+	ba, err := c.GetBankAccountContext(ctx)
+	if err != nil {
+		return err
+	}
+	transactions, err := c.GetAllTransactionsForAccountContext(ctx, ba, &qonto.GetTransactionOptions{
+		SettledAtFrom: &opt.From,
+		SettledAtTo:   &opt.To,
+	})
+	if err != nil {
+		return err
+	}
+
+	s, err := report.VATReport(transactions, opt)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *lines:
+		return s.WriteLinesCSV(os.Stdout)
+	case *format == "table":
+		return s.WriteTable(os.Stdout)
+	case *format == "csv":
+		return s.WriteCSV(os.Stdout)
+	case *format == "json":
+		return s.WriteJSON(os.Stdout)
+	default:
+		return fmt.Errorf("Unknown format %q", *format)
+	}
+}
@@ -0,0 +1,151 @@
+package qonto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCommandCredentialsTTL is the default value for CommandCredentials.TTL.
+const DefaultCommandCredentialsTTL = 5 * time.Minute
+
+// ErrMissingSecretKey is returned by the providers when no secret key is found.
+var ErrMissingSecretKey = errors.New("Missing secret key")
+
+// CredentialsProvider supplies the secret key of an organization.
+//
+// The Client asks for the key before each request, so the providers can rotate it without
+// restarting long-running services. Providers must be safe for concurrent use.
+type CredentialsProvider interface {
+	SecretKey(ctx context.Context) (string, error)
+}
+
+// StaticCredentials is a CredentialsProvider returning a fixed secret key.
+type StaticCredentials string
+
+// SecretKey returns the key.
+func (s StaticCredentials) SecretKey(ctx context.Context) (string, error) {
+	if s == "" {
+		return "", ErrMissingSecretKey
+	}
+	return string(s), nil
+}
+
+// EnvCredentials is a CredentialsProvider reading the secret key from an environment variable
+// (for eg. "QONTO_SECRET_KEY"), on each request.
+type EnvCredentials string
+
+// SecretKey reads the environment variable.
+func (e EnvCredentials) SecretKey(ctx context.Context) (string, error) {
+	key := os.Getenv(string(e))
+	if key == "" {
+		return "", fmt.Errorf("%w: $%s is empty", ErrMissingSecretKey, string(e))
+	}
+	return key, nil
+}
+
+// FileCredentials is a CredentialsProvider reading the secret key from a file, such as a
+// mounted secret. The file is read again whenever its modification time or size changes.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	key     string
+}
+
+// NewFileCredentials returns a provider reading the secret key from path.
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{path: path}
+}
+
+// SecretKey returns the content of the file, without the surrounding spaces.
+func (f *FileCredentials) SecretKey(ctx context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.key != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.key, nil
+	}
+	buf, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(buf))
+	if key == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrMissingSecretKey, f.path)
+	}
+	f.key, f.modTime, f.size = key, info.ModTime(), info.Size()
+	return key, nil
+}
+
+// CommandCredentials is a CredentialsProvider running a helper command, like the git
+// credential helpers. The key is read from the "password=" line of the output or, when
+// missing, from its first line. It is cached during TTL.
+type CommandCredentials struct {
+	Name string
+	Args []string
+	TTL  time.Duration // NewCommandCredentials sets it to DefaultCommandCredentialsTTL
+
+	mu      sync.Mutex
+	key     string
+	expires time.Time
+}
+
+// NewCommandCredentials returns a provider running name with args.
+func NewCommandCredentials(name string, args ...string) *CommandCredentials {
+	return &CommandCredentials{Name: name, Args: args, TTL: DefaultCommandCredentialsTTL}
+}
+
+// SecretKey returns the cached key, or runs the command.
+func (c *CommandCredentials) SecretKey(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key != "" && time.Now().Before(c.expires) {
+		return c.key, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", c.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	key := ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "password=") {
+			key = strings.TrimPrefix(line, "password=")
+			break
+		}
+		if first {
+			key = line
+		}
+	}
+	if key == "" {
+		return "", fmt.Errorf("%w: %s printed nothing", ErrMissingSecretKey, c.Name)
+	}
+	c.key, c.expires = key, time.Now().Add(c.TTL)
+	return key, nil
+}
+
+// Invalidate forgets the cached key, so the command runs again on the next request.
+func (c *CommandCredentials) Invalidate() {
+	c.mu.Lock()
+	c.key = ""
+	c.mu.Unlock()
+}
@@ -0,0 +1,74 @@
+package qonto_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	p := qonto.EnvCredentials("TEST_QONTO_SECRET_KEY")
+	if _, err := p.SecretKey(context.Background()); !errors.Is(err, qonto.ErrMissingSecretKey) {
+		t.Errorf("p.SecretKey() == %v; want ErrMissingSecretKey", err)
+	}
+	os.Setenv("TEST_QONTO_SECRET_KEY", "secret")
+	defer os.Unsetenv("TEST_QONTO_SECRET_KEY")
+	if key, err := p.SecretKey(context.Background()); key != "secret" || err != nil {
+		t.Errorf("p.SecretKey() == %q, %v; want %q", key, err, "secret")
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret_key")
+	if err := ioutil.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"organization":{"slug":"slug"}}`)
+	}))
+	defer srv.Close()
+	c := qonto.NewClientWithCredentials("slug", qonto.NewFileCredentials(path), nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	if _, err := c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed: %v", err)
+	}
+	// rotate the key
+	if err := ioutil.WriteFile(path, []byte("second, longer\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(path, later, later)
+	if _, err := c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed: %v", err)
+	}
+	if len(auth) != 2 || auth[0] != "slug:first" || auth[1] != "slug:second, longer" {
+		t.Errorf("Authorization headers == %q; want the rotated key", auth)
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	p := qonto.NewCommandCredentials("sh", "-c", "echo username=slug; echo password=from-helper")
+	if key, err := p.SecretKey(context.Background()); key != "from-helper" || err != nil {
+		t.Errorf("p.SecretKey() == %q, %v; want %q", key, err, "from-helper")
+	}
+	p = qonto.NewCommandCredentials("sh", "-c", "exit 1")
+	if _, err := p.SecretKey(context.Background()); err == nil {
+		t.Errorf("p.SecretKey() should fail when the command fails")
+	}
+}
@@ -0,0 +1,195 @@
+package qonto
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultEnricherConcurrency is the default value for Enricher.Concurrency.
+const DefaultEnricherConcurrency = 4
+
+// EnrichedTransaction is a Transaction with its related objects resolved.
+//
+// Unknown IDs (deleted labels, members who left etc.) are skipped.
+type EnrichedTransaction struct {
+	*Transaction
+	Initiator   *Membership   `json:"initiator,omitempty"`
+	Labels      []Label       `json:"labels,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+// Enricher resolves the memberships, labels and attachments of transactions.
+//
+// The labels and memberships are loaded once, on the first call to Enrich (or after Reset),
+// and the attachments are cached by ID. Note that the URL of a cached attachment expires
+// after some time: call Reset before downloading old attachments.
+//
+// An Enricher is safe for concurrent use.
+type Enricher struct {
+	c *Client
+	// Concurrency is the maximum number of attachments fetched in parallel (NewEnricher sets it to DefaultEnricherConcurrency)
+	Concurrency int
+	// SkipAttachments disables the resolution of attachments
+	SkipAttachments bool
+
+	mu          sync.Mutex
+	loaded      bool
+	labels      map[string]Label
+	memberships map[string]Membership
+	attachments map[string]*Attachment
+}
+
+// NewEnricher creates an Enricher using c to fetch the data.
+func NewEnricher(c *Client) *Enricher {
+	return &Enricher{
+		c:           c,
+		Concurrency: DefaultEnricherConcurrency,
+		attachments: make(map[string]*Attachment),
+	}
+}
+
+// Reset clears all the cached data.
+func (e *Enricher) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loaded = false
+	e.labels = nil
+	e.memberships = nil
+	e.attachments = make(map[string]*Attachment)
+}
+
+// Enrich resolves the related objects of all the transactions.
+func (e *Enricher) Enrich(ctx context.Context, transactions []*Transaction) ([]*EnrichedTransaction, error) {
+	if err := e.loadReferences(ctx); err != nil {
+		return nil, err
+	}
+	if !e.SkipAttachments {
+		if err := e.loadAttachments(ctx, transactions); err != nil {
+			return nil, err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	res := make([]*EnrichedTransaction, len(transactions))
+	for i, t := range transactions {
+		et := &EnrichedTransaction{Transaction: t}
+		if t.InitiatorID != nil {
+			if m, ok := e.memberships[*t.InitiatorID]; ok {
+				et.Initiator = &m
+			}
+		}
+		for _, id := range t.LabelIDs {
+			if l, ok := e.labels[id]; ok {
+				et.Labels = append(et.Labels, l)
+			}
+		}
+		if !e.SkipAttachments {
+			for _, id := range t.AttachmentIDs {
+				if a, ok := e.attachments[id]; ok {
+					et.Attachments = append(et.Attachments, a)
+				}
+			}
+		}
+		res[i] = et
+	}
+	return res, nil
+}
+
+// loadReferences fetches the labels and memberships, unless they are already loaded.
+func (e *Enricher) loadReferences(ctx context.Context) error {
+	e.mu.Lock()
+	loaded := e.loaded
+	e.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	labels, err := e.c.GetAllLabelsContext(ctx, 0, 0)
+	if err != nil {
+		return err
+	}
+	memberships, err := e.c.GetAllMembershipsContext(ctx, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.labels = make(map[string]Label, len(labels))
+	for _, l := range labels {
+		e.labels[l.ID] = l
+	}
+	e.memberships = make(map[string]Membership, len(memberships))
+	for _, m := range memberships {
+		e.memberships[m.ID] = m
+	}
+	e.loaded = true
+	return nil
+}
+
+// loadAttachments fetches the attachments missing from the cache, Concurrency at a time.
+func (e *Enricher) loadAttachments(ctx context.Context, transactions []*Transaction) error {
+	// list the attachments we need to fetch (once each)
+	e.mu.Lock()
+	var ids []string
+	seen := make(map[string]bool)
+	for _, t := range transactions {
+		for _, id := range t.AttachmentIDs {
+			if _, ok := e.attachments[id]; !ok && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	e.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultEnricherConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	sem := make(chan struct{}, concurrency)
+	for _, id := range ids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			a, err := e.c.GetAttachmentContext(ctx, id)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel() // ⬅︎ no need to continue
+				})
+				return
+			}
+			if a == nil {
+				return
+			}
+			e.mu.Lock()
+			e.attachments[id] = a
+			e.mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,73 @@
+package qonto_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// newReferenceServer serves labels, memberships and attachments, and counts the attachment requests.
+func newReferenceServer(t *testing.T, attachmentCalls *int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/labels", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"labels":[{"id":"l1","name":"Travel"}],"meta":{"current_page":1,"total_pages":1}}`)
+	})
+	mux.HandleFunc("/memberships", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"memberships":[{"id":"m1","first_name":"Jane","last_name":"Doe"}],"meta":{"current_page":1,"total_pages":1}}`)
+	})
+	mux.HandleFunc("/attachments/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(attachmentCalls, 1)
+		id := strings.TrimPrefix(r.URL.Path, "/attachments/")
+		fmt.Fprintf(w, `{"attachment":{"id":%q,"file_name":"%s.pdf"}}`, id, id)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestEnricher(t *testing.T) {
+	var attachmentCalls int32
+	srv := newReferenceServer(t, &attachmentCalls)
+	defer srv.Close()
+	initiator := "m1"
+	transactions := []*qonto.Transaction{
+		{ID: "t1", InitiatorID: &initiator, LabelIDs: []string{"l1", "deleted"}, AttachmentIDs: []string{"a1", "a2"}},
+		{ID: "t2", AttachmentIDs: []string{"a2", "a3"}},
+	}
+
+	c := qonto.NewClient("slug", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	e := qonto.NewEnricher(c)
+	res, err := e.Enrich(context.Background(), transactions)
+	if err != nil {
+		t.Fatalf("e.Enrich() failed: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("len(res) == %d; want %d", len(res), 2)
+	}
+	if res[0].Initiator == nil || res[0].Initiator.FirstName != "Jane" {
+		t.Errorf("res[0].Initiator == %v; want Jane", res[0].Initiator)
+	}
+	if len(res[0].Labels) != 1 || res[0].Labels[0].Name != "Travel" {
+		t.Errorf("res[0].Labels == %v; want [Travel]", res[0].Labels)
+	}
+	if len(res[1].Attachments) != 2 || res[1].Attachments[1].FileName != "a3.pdf" {
+		t.Errorf("res[1].Attachments == %v; want a2 and a3", res[1].Attachments)
+	}
+	if res[1].Initiator != nil {
+		t.Errorf("res[1].Initiator == %v; want nil", res[1].Initiator)
+	}
+
+	// every attachment is fetched once, even when enriching again
+	if _, err = e.Enrich(context.Background(), transactions); err != nil {
+		t.Fatalf("e.Enrich() failed: %v", err)
+	}
+	if attachmentCalls != 3 {
+		t.Errorf("attachmentCalls == %d; want %d", attachmentCalls, 3)
+	}
+}
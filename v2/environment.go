@@ -0,0 +1,43 @@
+package qonto
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SandboxBaseURL is the root URL of the API in the Sandbox environment.
+var SandboxBaseURL = "https://thirdparty-sandbox.staging.qonto.co/v2"
+
+// StagingTokenHeader holds the staging token required by the sandbox.
+const StagingTokenHeader = "X-Qonto-Staging-Token"
+
+// Environment describes where the API is reached.
+type Environment struct {
+	Name    string
+	BaseURL string      // the root URL of the API, or "" for the global BaseURL
+	Header  http.Header // headers added to every API request
+}
+
+// Production is the default environment, reached at BaseURL.
+var Production = Environment{Name: "production"}
+
+// Sandbox returns the environment of the Qonto sandbox, with the staging token of the developer account.
+func Sandbox(stagingToken string) Environment {
+	h := http.Header{}
+	h.Set(StagingTokenHeader, stagingToken)
+	return Environment{Name: "sandbox", BaseURL: SandboxBaseURL, Header: h}
+}
+
+// CustomEnvironment returns an environment reached at baseURL (for eg. a local stand-in),
+// adding header (which can be nil) to every request.
+func CustomEnvironment(name, baseURL string, header http.Header) Environment {
+	return Environment{Name: name, BaseURL: strings.TrimSuffix(baseURL, "/"), Header: header}
+}
+
+// baseURL returns the root URL of the API for the environment of c.
+func (c *Client) baseURL() string {
+	if c.Environment.BaseURL != "" {
+		return c.Environment.BaseURL
+	}
+	return BaseURL
+}
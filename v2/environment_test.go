@@ -0,0 +1,33 @@
+package qonto_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestEnvironment(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = fmt.Sprintf("%s %s", r.URL.Path, r.Header.Get(qonto.StagingTokenHeader))
+		fmt.Fprint(w, `{"organization":{"slug":"acme"}}`)
+	}))
+	defer srv.Close()
+
+	env := qonto.Sandbox("staging-token")
+	if env.BaseURL != qonto.SandboxBaseURL {
+		t.Errorf("env.BaseURL == %q; want %q", env.BaseURL, qonto.SandboxBaseURL)
+	}
+	// a local stand-in of the sandbox, the global BaseURL is left untouched
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL+"/v2/", env.Header)
+	if _, err := c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed: %v", err)
+	}
+	if want := "/v2/organizations/acme staging-token"; got != want {
+		t.Errorf("request == %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Package export writes Qonto transactions as CSV or TSV tables.
+
+The columns are selected by name (see Columns for the list), and the writer can
+resolve label and member names when given the reference data fetched with
+GetAllLabels and GetAllMemberships.
+
+Example:
+
+	labels, _ := c.GetAllLabels(0, 0)
+	members, _ := c.GetAllMemberships(0, 0)
+
+	w, _ := export.NewWriter(os.Stdout, export.Options{
+	   Columns:          []string{"emitted_at", "signed_amount", "label", "member", "label_names"},
+	   Comma:            ';',
+	   DecimalSeparator: ",",
+	   DateFormat:       "02/01/2006",
+	   Labels:           labels,
+	   Memberships:      members,
+	})
+	_ = w.WriteAll(transactions)
+*/
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// ErrUnknownColumn is returned by NewWriter when a column name is not listed in Columns.
+var ErrUnknownColumn = errors.New("Unknown export column")
+
+// Explode tells the Writer to emit several rows for a single transaction.
+type Explode int
+
+const (
+	// ExplodeNone writes exactly one row per transaction.
+	ExplodeNone Explode = iota
+	// ExplodeLabels writes one row per label attached to the transaction.
+	ExplodeLabels
+	// ExplodeAttachments writes one row per attachment of the transaction.
+	ExplodeAttachments
+)
+
+// DefaultColumns lists the columns written when Options.Columns is empty.
+var DefaultColumns = []string{
+	"id",
+	"emitted_at",
+	"settled_at",
+	"side",
+	"amount",
+	"currency",
+	"operation_type",
+	"status",
+	"label",
+	"note",
+}
+
+// Options configures a Writer.
+type Options struct {
+	// Columns lists the names of the columns to write, in order (defaults to DefaultColumns)
+	Columns []string
+	// Comma is the field delimiter (defaults to ',', use '\t' for TSV)
+	Comma rune
+	// DecimalSeparator is written between units and cents (defaults to ".")
+	DecimalSeparator string
+	// DateFormat is the time layout used for dates (defaults to time.RFC3339)
+	DateFormat string
+	// Location is used to convert dates before formatting (defaults to UTC)
+	Location *time.Location
+	// Explode allows to write one row per label or per attachment
+	Explode Explode
+	// SkipHeader disables the header row
+	SkipHeader bool
+	// Labels is used to resolve the label names from Transaction.LabelIDs
+	Labels []qonto.Label
+	// Memberships is used to resolve the member names from Transaction.InitiatorID
+	Memberships []qonto.Membership
+}
+
+// Row is the data available to a column when writing a single row.
+//
+// When the Writer explodes transactions, LabelID or AttachmentID holds the value for the current row.
+type Row struct {
+	Transaction  *qonto.Transaction
+	LabelID      string
+	AttachmentID string
+}
+
+// column formats a single cell for a Row.
+type column func(w *Writer, r *Row) string
+
+// Columns holds the names of all the columns accepted in Options.Columns.
+var Columns = []string{
+	"id", "emitted_at", "settled_at", "side", "amount", "signed_amount", "local_amount",
+	"currency", "local_currency", "operation_type", "status", "label", "note",
+	"vat_amount", "vat_rate", "initiator_id", "member", "label_ids", "label_names",
+	"attachment_ids", "attachment_count", "attachment_required", "label_id",
+	"label_name", "attachment_id",
+}
+
+var columns = map[string]column{
+	"id":           func(w *Writer, r *Row) string { return r.Transaction.ID },
+	"emitted_at":   func(w *Writer, r *Row) string { return w.formatTime(&r.Transaction.EmittedAt) },
+	"settled_at":   func(w *Writer, r *Row) string { return w.formatTime(r.Transaction.SettledAt) },
+	"side":         func(w *Writer, r *Row) string { return string(r.Transaction.Side) },
+	"amount":       func(w *Writer, r *Row) string { return w.formatCents(r.Transaction.AmountCents) },
+	"local_amount": func(w *Writer, r *Row) string { return w.formatCents(r.Transaction.LocalAmountCents) },
+	"signed_amount": func(w *Writer, r *Row) string {
+		if r.Transaction.Side == qonto.TransactionSideDebit {
+			return w.formatCents(-r.Transaction.AmountCents)
+		}
+		return w.formatCents(r.Transaction.AmountCents)
+	},
+	"currency":       func(w *Writer, r *Row) string { return r.Transaction.Currency },
+	"local_currency": func(w *Writer, r *Row) string { return r.Transaction.LocalCurrency },
+	"operation_type": func(w *Writer, r *Row) string { return string(r.Transaction.OperationType) },
+	"status":         func(w *Writer, r *Row) string { return string(r.Transaction.Status) },
+	"label":          func(w *Writer, r *Row) string { return stringValue(r.Transaction.Label) },
+	"note":           func(w *Writer, r *Row) string { return stringValue(r.Transaction.Note) },
+	"vat_amount": func(w *Writer, r *Row) string {
+		if r.Transaction.VATAmountCents == nil {
+			return ""
+		}
+		return w.formatCents(*r.Transaction.VATAmountCents)
+	},
+	"vat_rate": func(w *Writer, r *Row) string {
+		if r.Transaction.VATRate == nil {
+			return ""
+		}
+		return w.formatFloat(*r.Transaction.VATRate)
+	},
+	"initiator_id": func(w *Writer, r *Row) string { return stringValue(r.Transaction.InitiatorID) },
+	"member": func(w *Writer, r *Row) string {
+		if r.Transaction.InitiatorID == nil {
+			return ""
+		}
+		m, ok := w.members[*r.Transaction.InitiatorID]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(m.FirstName + " " + m.LastName)
+	},
+	"label_ids": func(w *Writer, r *Row) string { return strings.Join(r.Transaction.LabelIDs, "|") },
+	"label_names": func(w *Writer, r *Row) string {
+		names := make([]string, 0, len(r.Transaction.LabelIDs))
+		for _, id := range r.Transaction.LabelIDs {
+			names = append(names, w.labelName(id))
+		}
+		return strings.Join(names, "|")
+	},
+	"attachment_ids":      func(w *Writer, r *Row) string { return strings.Join(r.Transaction.AttachmentIDs, "|") },
+	"attachment_count":    func(w *Writer, r *Row) string { return strconv.Itoa(len(r.Transaction.AttachmentIDs)) },
+	"attachment_required": func(w *Writer, r *Row) string { return strconv.FormatBool(r.Transaction.AttachmentRequired) },
+	"label_id":            func(w *Writer, r *Row) string { return r.LabelID },
+	"label_name":          func(w *Writer, r *Row) string { return w.labelName(r.LabelID) },
+	"attachment_id":       func(w *Writer, r *Row) string { return r.AttachmentID },
+}
+
+// Writer writes transactions as rows of a CSV (or TSV) file.
+type Writer struct {
+	w             *csv.Writer
+	opt           Options
+	columns       []column
+	labels        map[string]qonto.Label
+	members       map[string]qonto.Membership
+	headerWritten bool
+}
+
+// NewWriter creates a Writer for the provided options.
+// It returns ErrUnknownColumn (wrapped) when a column is not listed in Columns.
+func NewWriter(w io.Writer, opt Options) (*Writer, error) {
+	if len(opt.Columns) == 0 {
+		opt.Columns = DefaultColumns
+	}
+	if opt.Comma == 0 {
+		opt.Comma = ','
+	}
+	if opt.DecimalSeparator == "" {
+		opt.DecimalSeparator = "."
+	}
+	if opt.DateFormat == "" {
+		opt.DateFormat = time.RFC3339
+	}
+	if opt.Location == nil {
+		opt.Location = time.UTC
+	}
+
+	cols := make([]column, len(opt.Columns))
+	for i, name := range opt.Columns {
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownColumn, name)
+		}
+		cols[i] = col
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = opt.Comma
+	ew := &Writer{
+		w:       cw,
+		opt:     opt,
+		columns: cols,
+		labels:  make(map[string]qonto.Label, len(opt.Labels)),
+		members: make(map[string]qonto.Membership, len(opt.Memberships)),
+	}
+	for _, l := range opt.Labels {
+		ew.labels[l.ID] = l
+	}
+	for _, m := range opt.Memberships {
+		ew.members[m.ID] = m
+	}
+	return ew, nil
+}
+
+// Write writes the row(s) for a single transaction.
+func (w *Writer) Write(t *qonto.Transaction) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if !w.opt.SkipHeader {
+			if err := w.w.Write(w.opt.Columns); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, r := range w.rows(t) {
+		record := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			record[i] = col(w, &r)
+		}
+		if err := w.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAll writes all the transactions and flushes the underlying writer.
+func (w *Writer) WriteAll(transactions []*qonto.Transaction) error {
+	for _, t := range transactions {
+		if err := w.Write(t); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// rows returns the rows to write for t, depending on the Explode option.
+// A transaction with no label (or attachment) is still written as a single row.
+func (w *Writer) rows(t *qonto.Transaction) []Row {
+	switch {
+	case w.opt.Explode == ExplodeLabels && len(t.LabelIDs) > 0:
+		rows := make([]Row, len(t.LabelIDs))
+		for i, id := range t.LabelIDs {
+			rows[i] = Row{Transaction: t, LabelID: id}
+		}
+		return rows
+	case w.opt.Explode == ExplodeAttachments && len(t.AttachmentIDs) > 0:
+		rows := make([]Row, len(t.AttachmentIDs))
+		for i, id := range t.AttachmentIDs {
+			rows[i] = Row{Transaction: t, AttachmentID: id}
+		}
+		return rows
+	default:
+		return []Row{{Transaction: t}}
+	}
+}
+
+func (w *Writer) labelName(id string) string {
+	if l, ok := w.labels[id]; ok {
+		return l.Name
+	}
+	return ""
+}
+
+func (w *Writer) formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.In(w.opt.Location).Format(w.opt.DateFormat)
+}
+
+// formatCents formats an amount in cents without going through float64, so that the
+// exported value is exact.
+func (w *Writer) formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d%s%02d", sign, cents/100, w.opt.DecimalSeparator, cents%100)
+}
+
+func (w *Writer) formatFloat(f float64) string {
+	return strings.Replace(strconv.FormatFloat(f, 'f', -1, 64), ".", w.opt.DecimalSeparator, 1)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
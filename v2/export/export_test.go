@@ -0,0 +1,87 @@
+package export_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/export"
+)
+
+func testTransaction() *qonto.Transaction {
+	label := "SOME DEBIT"
+	initiator := "member-1"
+	vat := int64(2007)
+	rate := 20.0
+	return &qonto.Transaction{
+		ID:             "transaction-1",
+		AmountCents:    12042,
+		Side:           qonto.TransactionSideDebit,
+		Currency:       "EUR",
+		EmittedAt:      time.Date(2018, 10, 1, 23, 30, 0, 0, time.UTC),
+		Status:         qonto.TransactionStatusCompleted,
+		Label:          &label,
+		InitiatorID:    &initiator,
+		VATAmountCents: &vat,
+		VATRate:        &rate,
+		LabelIDs:       []string{"label-1", "label-2"},
+		AttachmentIDs:  []string{"attachment-1"},
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	paris, _ := time.LoadLocation("Europe/Paris")
+	w, err := export.NewWriter(&buf, export.Options{
+		Columns:          []string{"id", "emitted_at", "signed_amount", "vat_amount", "vat_rate", "member", "label_names"},
+		Comma:            ';',
+		DecimalSeparator: ",",
+		DateFormat:       "02/01/2006",
+		Location:         paris,
+		Labels:           []qonto.Label{{ID: "label-1", Name: "Travel"}, {ID: "label-2", Name: "Train"}},
+		Memberships:      []qonto.Membership{{ID: "member-1", FirstName: "Jane", LastName: "Doe"}},
+	})
+	if err != nil {
+		t.Fatalf("export.NewWriter() failed: %v", err)
+	}
+	if err = w.WriteAll([]*qonto.Transaction{testTransaction()}); err != nil {
+		t.Fatalf("w.WriteAll() failed: %v", err)
+	}
+
+	want := "id;emitted_at;signed_amount;vat_amount;vat_rate;member;label_names\n" +
+		"transaction-1;02/10/2018;-120,42;20,07;20;Jane Doe;Travel|Train\n"
+	if buf.String() != want {
+		t.Errorf("output == %q; want %q", buf.String(), want)
+	}
+}
+
+func TestWriter_ExplodeLabels(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, export.Options{
+		Columns:    []string{"id", "label_id", "label_name"},
+		Comma:      '\t',
+		Explode:    export.ExplodeLabels,
+		SkipHeader: true,
+		Labels:     []qonto.Label{{ID: "label-1", Name: "Travel"}},
+	})
+	if err != nil {
+		t.Fatalf("export.NewWriter() failed: %v", err)
+	}
+	if err = w.WriteAll([]*qonto.Transaction{testTransaction()}); err != nil {
+		t.Fatalf("w.WriteAll() failed: %v", err)
+	}
+
+	want := "transaction-1\tlabel-1\tTravel\ntransaction-1\tlabel-2\t\n"
+	if buf.String() != want {
+		t.Errorf("output == %q; want %q", buf.String(), want)
+	}
+}
+
+func TestNewWriter_UnknownColumn(t *testing.T) {
+	_, err := export.NewWriter(&bytes.Buffer{}, export.Options{Columns: []string{"nope"}})
+	if !errors.Is(err, export.ErrUnknownColumn) {
+		t.Errorf("err == %v; want %v", err, export.ErrUnknownColumn)
+	}
+}
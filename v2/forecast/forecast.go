@@ -0,0 +1,340 @@
+/*
+Package forecast projects the balance of a Qonto account, day by day.
+
+The projection starts from the authorized balance (the balance minus the pending transactions,
+which are not settled yet), and applies the subscriptions detected in the
+history of the account (see analysis.DetectRecurring) and the scheduled items supplied by the
+user, such as salaries or expected customer payments:
+
+	p, err := forecast.Forecast(ba, transactions, forecast.Options{
+		Scheduled:       items,
+		LowBalanceCents: 500000,
+	})
+	for _, a := range p.Alerts {
+		fmt.Printf("balance below 5000.00 from %s\n", a.Date.Format("2006-01-02"))
+	}
+*/
+package forecast
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/analysis"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+// DefaultHorizon is the default number of projected days.
+const DefaultHorizon = 90
+
+// ErrInvalidSchedule is returned by LoadSchedule on malformed files.
+var ErrInvalidSchedule = errors.New("Invalid schedule")
+
+// Source tells where a flow comes from.
+type Source string
+
+const (
+	// SourceRecurring is a flow predicted from a detected subscription
+	SourceRecurring Source = "recurring"
+	// SourceScheduled is a flow supplied by the user
+	SourceScheduled Source = "scheduled"
+)
+
+// ScheduledItem is a future flow known by the user.
+type ScheduledItem struct {
+	Date        time.Time
+	Label       string
+	AmountCents int64 // negative for outgoing flows
+	// Repeat is the period of the item, or "" for a single occurrence
+	Repeat analysis.Period
+}
+
+// Flow is a projected change of the balance.
+type Flow struct {
+	Label       string `json:"label"`
+	AmountCents int64  `json:"amount_cents"`
+	Source      Source `json:"source"`
+}
+
+// Day is the projected balance at the end of a day.
+type Day struct {
+	Date         time.Time `json:"date"`
+	BalanceCents int64     `json:"balance_cents"`
+	Flows        []Flow    `json:"flows,omitempty"`
+	Low          bool      `json:"low,omitempty"`
+}
+
+// Alert marks the first day of a period with a low balance.
+type Alert struct {
+	Date time.Time `json:"date"`
+	// BalanceCents is the lowest balance of the period
+	BalanceCents int64 `json:"balance_cents"`
+	// Days is the length of the period
+	Days int `json:"days"`
+}
+
+// Options configures Forecast.
+type Options struct {
+	// Start is the first projected day (defaults to today)
+	Start time.Time
+	// Horizon is the number of projected days (defaults to DefaultHorizon)
+	Horizon int
+	// Location is used to compute the days (defaults to UTC)
+	Location *time.Location
+	// LowBalanceCents is the threshold of the alerts
+	LowBalanceCents int64
+	// Scheduled lists the flows known by the user
+	Scheduled []ScheduledItem
+	// Recurring configures the detection of the subscriptions
+	Recurring analysis.RecurringOptions
+	// SkipRecurring disables the detection of the subscriptions
+	SkipRecurring bool
+}
+
+// Projection is the result of Forecast.
+type Projection struct {
+	StartBalanceCents int64                   `json:"start_balance_cents"`
+	Days              []Day                   `json:"days"`
+	Alerts            []Alert                 `json:"alerts,omitempty"`
+	Subscriptions     []analysis.Subscription `json:"-"`
+}
+
+// Forecast projects the balance of ba over the horizon, starting from its authorized balance
+// so that the pending debits are already deducted.
+//
+// The subscriptions are detected in transactions (the history of the account, which must include
+// the pending transactions so that their charges are not projected again), and the stopped ones
+// are ignored. An overdue charge of an active subscription is projected on the first day.
+func Forecast(ba *qonto.BankAccount, transactions []*qonto.Transaction, opt Options) (*Projection, error) {
+	if ba == nil {
+		return nil, qonto.ErrBankAccountNeeded
+	}
+	if opt.Location == nil {
+		opt.Location = time.UTC
+	}
+	if opt.Start.IsZero() {
+		opt.Start = time.Now()
+	}
+	if opt.Horizon <= 0 {
+		opt.Horizon = DefaultHorizon
+	}
+	start := startOfDay(opt.Start, opt.Location)
+	end := start.AddDate(0, 0, opt.Horizon)
+
+	p := &Projection{StartBalanceCents: ba.AuthorizedBalanceCents}
+	flows := make(map[time.Time][]Flow)
+	add := func(at time.Time, f Flow) {
+		day := startOfDay(at, opt.Location)
+		if day.Before(start) {
+			day = start
+		}
+		flows[day] = append(flows[day], f)
+	}
+
+	if !opt.SkipRecurring {
+		if opt.Recurring.Now.IsZero() {
+			opt.Recurring.Now = opt.Start
+		}
+		for _, s := range analysis.DetectRecurring(transactions, opt.Recurring) {
+			if s.Stopped {
+				continue
+			}
+			p.Subscriptions = append(p.Subscriptions, s)
+			// the charges are computed from the first one, to keep the end of month charges
+			for n, at := 0, s.NextCharge; at.Before(end); n, at = n+1, s.Charge(n+1) {
+				add(at, Flow{Label: s.Label, AmountCents: -s.NextAmountCents, Source: SourceRecurring})
+			}
+		}
+	}
+	for _, item := range opt.Scheduled {
+		// single occurrences (and unknown periods) do not move forward
+		repeats := item.Repeat.Next(item.Date).After(item.Date)
+		for n, at := 0, item.Date; at.Before(end); n, at = n+1, item.Repeat.Add(item.Date, n+1) {
+			if !startOfDay(at, opt.Location).Before(start) {
+				add(at, Flow{Label: item.Label, AmountCents: item.AmountCents, Source: SourceScheduled})
+			}
+			if !repeats {
+				break
+			}
+		}
+	}
+
+	balance := ba.AuthorizedBalanceCents
+	var alert *Alert
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		day := Day{Date: d, Flows: flows[d]}
+		for _, f := range day.Flows {
+			balance += f.AmountCents
+		}
+		day.BalanceCents = balance
+		day.Low = balance < opt.LowBalanceCents
+		p.Days = append(p.Days, day)
+
+		switch {
+		case day.Low && alert == nil:
+			p.Alerts = append(p.Alerts, Alert{Date: d, BalanceCents: balance, Days: 1})
+			alert = &p.Alerts[len(p.Alerts)-1]
+		case day.Low:
+			alert.Days++
+			if balance < alert.BalanceCents {
+				alert.BalanceCents = balance
+			}
+		default:
+			alert = nil
+		}
+	}
+	sortFlows(p.Days)
+	return p, nil
+}
+
+// LoadSchedule reads scheduled items from a CSV file with the "date,label,amount,repeat"
+// columns and a header line, for eg.:
+//
+//	date,label,amount,repeat
+//	2020-01-28,Salaries,-12000.00,monthly
+//	2020-02-15,ACME invoice 42,3600.00,
+//
+// Dates are read in loc (defaults to UTC).
+func LoadSchedule(r io.Reader, loc *time.Location) ([]ScheduledItem, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	var items []ScheduledItem
+	for i, record := range records {
+		if i == 0 {
+			continue // header
+		}
+		date, err := time.ParseInLocation("2006-01-02", record[0], loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidSchedule, i+1, err)
+		}
+		cents, err := parseCents(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidSchedule, i+1, err)
+		}
+		repeat := analysis.Period(record[3])
+		switch repeat {
+		case "", analysis.PeriodWeekly, analysis.PeriodMonthly, analysis.PeriodYearly:
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown period %q", ErrInvalidSchedule, i+1, repeat)
+		}
+		items = append(items, ScheduledItem{Date: date, Label: record[1], AmountCents: cents, Repeat: repeat})
+	}
+	return items, nil
+}
+
+// parseCents parses a decimal amount ("-1234.5") as cents, without rounding errors.
+func parseCents(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	parts := strings.SplitN(s, ".", 2)
+	units, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	var cents int64
+	if len(parts) == 2 {
+		if len(parts[1]) == 0 || len(parts[1]) > 2 {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		if cents, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return 0, err
+		}
+		if len(parts[1]) == 1 {
+			cents *= 10
+		}
+	}
+	cents += units * 100
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// WriteJSON writes the projection as indented JSON.
+func (p *Projection) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteCSV writes the projected balances as CSV, amounts in cents.
+func (p *Projection) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{"date", "balance_cents", "flows_cents", "low"}}
+	for _, d := range p.Days {
+		var total int64
+		for _, f := range d.Flows {
+			total += f.AmountCents
+		}
+		records = append(records, []string{
+			d.Date.Format("2006-01-02"),
+			strconv.FormatInt(d.BalanceCents, 10),
+			strconv.FormatInt(total, 10),
+			strconv.FormatBool(d.Low),
+		})
+	}
+	return cw.WriteAll(records)
+}
+
+// WriteTable writes the days with flows as an aligned text table, followed by the alerts.
+func (p *Projection) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tLABEL\tAMOUNT\tBALANCE\t")
+	fmt.Fprintf(tw, "\tcurrent balance\t\t%s\t\n", report.FormatCents(p.StartBalanceCents))
+	for _, d := range p.Days {
+		for i, f := range d.Flows {
+			balance := ""
+			if i == len(d.Flows)-1 {
+				balance = report.FormatCents(d.BalanceCents)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", d.Date.Format("2006-01-02"), f.Label, report.FormatCents(f.AmountCents), balance)
+		}
+	}
+	if len(p.Days) > 0 {
+		last := p.Days[len(p.Days)-1]
+		fmt.Fprintf(tw, "%s\tprojected balance\t\t%s\t\n", last.Date.Format("2006-01-02"), report.FormatCents(last.BalanceCents))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, a := range p.Alerts {
+		if _, err := fmt.Fprintf(w, "\nLow balance from %s for %d day(s), down to %s", a.Date.Format("2006-01-02"), a.Days, report.FormatCents(a.BalanceCents)); err != nil {
+			return err
+		}
+	}
+	if len(p.Alerts) > 0 {
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	return nil
+}
+
+// sortFlows sorts the flows of each day by label, for stable outputs.
+func sortFlows(days []Day) {
+	for _, d := range days {
+		sort.SliceStable(d.Flows, func(i, j int) bool { return d.Flows[i].Label < d.Flows[j].Label })
+	}
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
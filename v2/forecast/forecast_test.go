@@ -0,0 +1,147 @@
+package forecast_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/analysis"
+	"github.com/ushu/qonto-go/v2/forecast"
+)
+
+func debit(label string, cents int64, at time.Time) *qonto.Transaction {
+	return &qonto.Transaction{
+		ID:          label + at.Format("20060102"),
+		Label:       &label,
+		AmountCents: cents,
+		Side:        qonto.TransactionSideDebit,
+		Status:      qonto.TransactionStatusCompleted,
+		EmittedAt:   at,
+	}
+}
+
+func TestForecast(t *testing.T) {
+	start := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	var transactions []*qonto.Transaction
+	for i := 1; i <= 3; i++ {
+		transactions = append(transactions, debit("RENT", 100000, start.AddDate(0, -i, 4)))
+		transactions = append(transactions, debit("OLD SAAS", 1000, start.AddDate(0, -i-3, 0))) // stopped
+	}
+	schedule := `date,label,amount,repeat
+2020-04-10,Salaries,-1500.00,monthly
+2020-04-20,ACME,2000,
+2020-03-01,Past,-1.00,
+`
+	items, err := forecast.LoadSchedule(strings.NewReader(schedule), nil)
+	if err != nil {
+		t.Fatalf("forecast.LoadSchedule() failed: %v", err)
+	}
+	if len(items) != 3 || items[0].AmountCents != -150000 || items[0].Repeat != analysis.PeriodMonthly || items[1].AmountCents != 200000 {
+		t.Fatalf("items == %+v", items)
+	}
+
+	// a pending debit of 100.00 is not settled yet
+	ba := &qonto.BankAccount{BalanceCents: 310000, AuthorizedBalanceCents: 300000}
+	p, err := forecast.Forecast(ba, transactions, forecast.Options{
+		Start:           start,
+		Horizon:         30,
+		Scheduled:       items,
+		LowBalanceCents: 100000,
+	})
+	if err != nil {
+		t.Fatalf("forecast.Forecast() failed: %v", err)
+	}
+	if len(p.Days) != 30 {
+		t.Fatalf("len(p.Days) == %d; want %d", len(p.Days), 30)
+	}
+	if len(p.Subscriptions) != 1 || p.Subscriptions[0].Label != "RENT" {
+		t.Errorf("p.Subscriptions == %v; want RENT only", p.Subscriptions)
+	}
+
+	balances := map[int]int64{0: 300000, 4: 200000, 9: 50000, 19: 250000, 29: 250000}
+	for day, want := range balances {
+		if got := p.Days[day].BalanceCents; got != want {
+			t.Errorf("p.Days[%d].BalanceCents == %d; want %d", day, got, want)
+		}
+	}
+	if len(p.Alerts) != 1 || !p.Alerts[0].Date.Equal(start.AddDate(0, 0, 9)) || p.Alerts[0].Days != 10 || p.Alerts[0].BalanceCents != 50000 {
+		t.Errorf("p.Alerts == %+v; want a 10 days alert from 2020-04-10", p.Alerts)
+	}
+
+	var buf bytes.Buffer
+	if err = p.WriteTable(&buf); err != nil {
+		t.Fatalf("p.WriteTable() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Low balance from 2020-04-10 for 10 day(s), down to 500.00") {
+		t.Errorf("p.WriteTable() wrote:\n%s", buf.String())
+	}
+}
+
+func TestLoadSchedule_Invalid(t *testing.T) {
+	_, err := forecast.LoadSchedule(strings.NewReader("date,label,amount,repeat\n2020-01-01,X,1.00,daily\n"), nil)
+	if !errors.Is(err, forecast.ErrInvalidSchedule) {
+		t.Errorf("forecast.LoadSchedule() == %v; want ErrInvalidSchedule", err)
+	}
+}
+
+func TestForecast_PendingCharge(t *testing.T) {
+	start := time.Date(2020, 4, 8, 0, 0, 0, 0, time.UTC)
+	var transactions []*qonto.Transaction
+	for i := 0; i <= 3; i++ {
+		transactions = append(transactions, debit("RENT", 100000, time.Date(2020, 1+time.Month(i), 5, 0, 0, 0, 0, time.UTC)))
+	}
+	// the charge of April is pending, and already deducted from the authorized balance
+	transactions[3].Status = qonto.TransactionStatusPending
+	ba := &qonto.BankAccount{BalanceCents: 400000, AuthorizedBalanceCents: 300000}
+	p, err := forecast.Forecast(ba, transactions, forecast.Options{Start: start, Horizon: 30})
+	if err != nil {
+		t.Fatalf("forecast.Forecast() failed: %v", err)
+	}
+	if got := p.Days[0].BalanceCents; got != 300000 {
+		t.Errorf("p.Days[0].BalanceCents == %d; want %d", got, 300000)
+	}
+	if got := p.Days[27].BalanceCents; got != 200000 {
+		t.Errorf("p.Days[27].BalanceCents == %d on 2020-05-05; want %d", got, 200000)
+	}
+}
+
+func TestForecast_EndOfMonth(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []forecast.ScheduledItem{
+		{Date: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC), Label: "Salaries", AmountCents: -100, Repeat: analysis.PeriodMonthly},
+		{Date: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC), Label: "Insurance", AmountCents: -10, Repeat: analysis.PeriodYearly},
+	}
+	p, err := forecast.Forecast(&qonto.BankAccount{}, nil, forecast.Options{Start: start, Horizon: 800, Scheduled: items, SkipRecurring: true})
+	if err != nil {
+		t.Fatalf("forecast.Forecast() failed: %v", err)
+	}
+	var got []string
+	for _, d := range p.Days {
+		for _, f := range d.Flows {
+			got = append(got, f.Label+" "+d.Date.Format("2006-01-02"))
+		}
+	}
+	for _, want := range []string{
+		"Salaries 2020-01-31", "Salaries 2020-02-29", "Salaries 2020-03-31", "Salaries 2020-04-30", "Salaries 2021-02-28",
+		"Insurance 2020-02-29", "Insurance 2021-02-28", "Insurance 2022-02-28",
+	} {
+		if !contains(got, want) {
+			t.Errorf("flows == %v; want %s", got, want)
+		}
+	}
+	if contains(got, "Salaries 2020-03-29") || contains(got, "Insurance 2021-03-01") {
+		t.Errorf("flows == %v; want no drift", got)
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
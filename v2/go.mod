@@ -1,8 +1,9 @@
 module github.com/ushu/qonto-go/v2
 
-go 1.14
+go 1.21
 
 require (
 	github.com/labstack/gommon v0.3.0
 	github.com/ushu/qonto-go v0.0.0-20181003143659-aa5a84325bca
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,217 @@
+package qonto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LabelPathSeparator separates the label names in a label path ("Ops/Travel/Train").
+const LabelPathSeparator = "/"
+
+// ErrLabelCycle is reported for labels which ancestors include themselves.
+var ErrLabelCycle = errors.New("Label is its own ancestor")
+
+// ErrDanglingLabelParent is reported for labels which parent does not exist.
+var ErrDanglingLabelParent = errors.New("Label parent does not exist")
+
+// LabelError describes an inconsistency of the label hierarchy.
+type LabelError struct {
+	LabelID string
+	Err     error // ErrLabelCycle or ErrDanglingLabelParent
+}
+
+func (e *LabelError) Error() string {
+	return fmt.Sprintf("label %s: %s", e.LabelID, e.Err.Error())
+}
+
+func (e *LabelError) Unwrap() error {
+	return e.Err
+}
+
+// LabelTree organizes the labels of an Organization in a hierarchy, following Label.ParentID.
+//
+// Labels with a missing parent, or part of a cycle, are considered as roots of the tree and
+// reported by Errors.
+type LabelTree struct {
+	labels   map[string]Label
+	parents  map[string]string   // label ID ➡︎ parent ID (only for valid parents)
+	children map[string][]string // label ID ➡︎ children IDs ("" for the roots)
+	paths    map[string]string   // label ID ➡︎ path
+	byPath   map[string]string   // path ➡︎ label ID
+	errors   []*LabelError
+}
+
+// NewLabelTree builds a LabelTree from a flat list of labels, as returned by GetAllLabels.
+func NewLabelTree(labels []Label) *LabelTree {
+	t := &LabelTree{
+		labels:   make(map[string]Label, len(labels)),
+		parents:  make(map[string]string, len(labels)),
+		children: make(map[string][]string),
+		paths:    make(map[string]string, len(labels)),
+		byPath:   make(map[string]string, len(labels)),
+	}
+	for _, l := range labels {
+		t.labels[l.ID] = l
+	}
+
+	// sorted IDs make the tree (and the reported errors) deterministic
+	ids := make([]string, 0, len(t.labels))
+	for id := range t.labels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		l := t.labels[id]
+		switch {
+		case l.ParentID == nil || *l.ParentID == "":
+		case !t.has(*l.ParentID):
+			t.errors = append(t.errors, &LabelError{LabelID: id, Err: ErrDanglingLabelParent})
+		case t.inCycle(id):
+			t.errors = append(t.errors, &LabelError{LabelID: id, Err: ErrLabelCycle})
+		default:
+			t.parents[id] = *l.ParentID
+		}
+	}
+	for _, id := range ids {
+		parent := t.parents[id]
+		t.children[parent] = append(t.children[parent], id)
+	}
+	for parent := range t.children {
+		t.sortByName(t.children[parent])
+	}
+	for _, id := range ids {
+		var names []string
+		for _, a := range t.Ancestors(id) {
+			names = append([]string{a.Name}, names...)
+		}
+		p := strings.Join(append(names, t.labels[id].Name), LabelPathSeparator)
+		t.paths[id] = p
+		if _, ok := t.byPath[p]; !ok {
+			t.byPath[p] = id
+		}
+	}
+	return t
+}
+
+// GetLabelTree fetches all the labels of the current Organization, and organizes them in a LabelTree.
+func (c *Client) GetLabelTree() (*LabelTree, error) {
+	return c.GetLabelTreeContext(context.Background())
+}
+
+// GetLabelTreeContext fetches all the labels of the current Organization, and organizes them in a LabelTree.
+func (c *Client) GetLabelTreeContext(ctx context.Context) (*LabelTree, error) {
+	labels, err := c.GetAllLabelsContext(ctx, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewLabelTree(labels), nil
+}
+
+// Errors lists the inconsistencies found in the hierarchy (cycles, missing parents).
+func (t *LabelTree) Errors() []*LabelError {
+	return t.errors
+}
+
+// Label returns the label with the provided id.
+func (t *LabelTree) Label(id string) (Label, bool) {
+	l, ok := t.labels[id]
+	return l, ok
+}
+
+// LabelByPath returns the label at path ("Ops/Travel/Train").
+// When several labels share the same path, the one with the smallest ID is returned.
+func (t *LabelTree) LabelByPath(path string) (Label, bool) {
+	id, ok := t.byPath[path]
+	if !ok {
+		return Label{}, false
+	}
+	return t.labels[id], true
+}
+
+// Path returns the full path of the label with the provided id, or "" for unknown labels.
+func (t *LabelTree) Path(id string) string {
+	return t.paths[id]
+}
+
+// Roots lists the labels without parent, sorted by name.
+func (t *LabelTree) Roots() []Label {
+	return t.list(t.children[""])
+}
+
+// Children lists the direct children of the label with the provided id, sorted by name.
+func (t *LabelTree) Children(id string) []Label {
+	return t.list(t.children[id])
+}
+
+// Ancestors lists the ancestors of the label with the provided id, from its parent to its root.
+func (t *LabelTree) Ancestors(id string) []Label {
+	var res []Label
+	for parent, ok := t.parents[id]; ok; parent, ok = t.parents[parent] {
+		res = append(res, t.labels[parent])
+	}
+	return res
+}
+
+// Descendants lists all the descendants of the label with the provided id, depth first.
+func (t *LabelTree) Descendants(id string) []Label {
+	var res []Label
+	for _, child := range t.children[id] {
+		res = append(res, t.labels[child])
+		res = append(res, t.Descendants(child)...)
+	}
+	return res
+}
+
+// TransactionPaths resolves the LabelIDs of tr into full paths.
+// Unknown labels are skipped.
+func (t *LabelTree) TransactionPaths(tr *Transaction) []string {
+	var res []string
+	for _, id := range tr.LabelIDs {
+		if p, ok := t.paths[id]; ok {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func (t *LabelTree) has(id string) bool {
+	_, ok := t.labels[id]
+	return ok
+}
+
+// inCycle reports whether following the parents from id leads back to id.
+func (t *LabelTree) inCycle(id string) bool {
+	visited := map[string]bool{id: true}
+	for cur := t.labels[id].ParentID; cur != nil && t.has(*cur); cur = t.labels[*cur].ParentID {
+		if *cur == id {
+			return true
+		}
+		if visited[*cur] {
+			return false // ⬅︎ a cycle above id, that does not include id
+		}
+		visited[*cur] = true
+	}
+	return false
+}
+
+func (t *LabelTree) sortByName(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		ni, nj := t.labels[ids[i]].Name, t.labels[ids[j]].Name
+		if ni == nj {
+			return ids[i] < ids[j]
+		}
+		return ni < nj
+	})
+}
+
+func (t *LabelTree) list(ids []string) []Label {
+	res := make([]Label, len(ids))
+	for i, id := range ids {
+		res[i] = t.labels[id]
+	}
+	return res
+}
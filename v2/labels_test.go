@@ -0,0 +1,74 @@
+package qonto_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func testLabels() []qonto.Label {
+	parent := func(id string) *string { return &id }
+	return []qonto.Label{
+		{ID: "ops", Name: "Ops"},
+		{ID: "travel", Name: "Travel", ParentID: parent("ops")},
+		{ID: "train", Name: "Train", ParentID: parent("travel")},
+		{ID: "plane", Name: "Plane", ParentID: parent("travel")},
+		{ID: "orphan", Name: "Orphan", ParentID: parent("missing")},
+		{ID: "a", Name: "A", ParentID: parent("b")},
+		{ID: "b", Name: "B", ParentID: parent("a")},
+	}
+}
+
+func TestLabelTree(t *testing.T) {
+	tree := qonto.NewLabelTree(testLabels())
+
+	if p := tree.Path("train"); p != "Ops/Travel/Train" {
+		t.Errorf("tree.Path(train) == %q; want %q", p, "Ops/Travel/Train")
+	}
+	if l, ok := tree.LabelByPath("Ops/Travel/Plane"); !ok || l.ID != "plane" {
+		t.Errorf("tree.LabelByPath(Ops/Travel/Plane) == %v, %v; want plane", l, ok)
+	}
+	if _, ok := tree.LabelByPath("Ops/Plane"); ok {
+		t.Errorf("tree.LabelByPath(Ops/Plane) should not be found")
+	}
+
+	ancestors := tree.Ancestors("train")
+	if len(ancestors) != 2 || ancestors[0].ID != "travel" || ancestors[1].ID != "ops" {
+		t.Errorf("tree.Ancestors(train) == %v; want [travel ops]", ancestors)
+	}
+	descendants := tree.Descendants("ops")
+	if len(descendants) != 3 || descendants[0].ID != "travel" || descendants[1].ID != "plane" || descendants[2].ID != "train" {
+		t.Errorf("tree.Descendants(ops) == %v; want [travel plane train]", descendants)
+	}
+
+	tr := &qonto.Transaction{LabelIDs: []string{"train", "unknown", "ops"}}
+	paths := tree.TransactionPaths(tr)
+	if len(paths) != 2 || paths[0] != "Ops/Travel/Train" || paths[1] != "Ops" {
+		t.Errorf("tree.TransactionPaths() == %v; want [Ops/Travel/Train Ops]", paths)
+	}
+}
+
+func TestLabelTree_Errors(t *testing.T) {
+	tree := qonto.NewLabelTree(testLabels())
+
+	errs := tree.Errors()
+	if len(errs) != 3 {
+		t.Fatalf("tree.Errors() == %v; want 3 errors", errs)
+	}
+	want := map[string]error{"a": qonto.ErrLabelCycle, "b": qonto.ErrLabelCycle, "orphan": qonto.ErrDanglingLabelParent}
+	for _, err := range errs {
+		if !errors.Is(err, want[err.LabelID]) {
+			t.Errorf("error for %s == %v; want %v", err.LabelID, err.Err, want[err.LabelID])
+		}
+	}
+
+	// invalid labels become roots
+	roots := tree.Roots()
+	if len(roots) != 4 {
+		t.Errorf("tree.Roots() == %v; want 4 roots", roots)
+	}
+	if p := tree.Path("orphan"); p != "Orphan" {
+		t.Errorf("tree.Path(orphan) == %q; want %q", p, "Orphan")
+	}
+}
@@ -0,0 +1,99 @@
+package qonto
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ibanParam matches the IBANs in the query strings of the URLs.
+var ibanParam = regexp.MustCompile(`(?i)([?&]iban=)([^&\s"]+)`)
+
+// MaskIBAN hides an IBAN but for its country code, check digits and 4 last characters.
+func MaskIBAN(iban string) string {
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(iban) <= 8 {
+		return strings.Repeat("*", len(iban))
+	}
+	return iban[:4] + strings.Repeat("*", len(iban)-8) + iban[len(iban)-4:]
+}
+
+// RedactError returns the message of err without the secrets of the request URLs: the *url.Error
+// of the transport are reduced to their operation and cause, and the IBANs and the signatures of
+// pre-signed URLs left in the message are masked.
+func RedactError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		msg = strings.Replace(msg, ue.Error(), ue.Op+": "+ue.Err.Error(), 1)
+	}
+	return Redact(maskIBANs(msg))
+}
+
+// maskIBANs masks the IBANs of the query strings found in s.
+func maskIBANs(s string) string {
+	return ibanParam.ReplaceAllStringFunc(s, func(param string) string {
+		m := ibanParam.FindStringSubmatch(param)
+		iban, err := url.QueryUnescape(m[2])
+		if err != nil {
+			iban = m[2]
+		}
+		return m[1] + MaskIBAN(iban)
+	})
+}
+
+// logRequest logs a request sent to the API or, when presigned is true, to a pre-signed
+// attachment URL (the query holding the signature is never logged).
+func (c *Client) logRequest(req *http.Request, res *http.Response, err error, latency time.Duration, attempt int, presigned bool) {
+	if c.Logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+	}
+	if presigned {
+		attrs = append(attrs, slog.String("host", req.URL.Host))
+	} else if query := req.URL.Query(); len(query) > 0 {
+		if page := query.Get("current_page"); page != "" {
+			attrs = append(attrs, slog.String("page", page))
+		}
+		attrs = append(attrs, slog.String("query", logQuery(query)))
+	}
+	attrs = append(attrs, slog.Duration("latency", latency), slog.Int("attempt", attempt))
+
+	ctx := req.Context()
+	switch {
+	case err != nil:
+		attrs = append(attrs, slog.String("error", RedactError(err)))
+		c.Logger.LogAttrs(ctx, slog.LevelError, "qonto request failed", attrs...)
+	case res.StatusCode > 299:
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+		c.Logger.LogAttrs(ctx, slog.LevelWarn, "qonto request", attrs...)
+	default:
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+		c.Logger.LogAttrs(ctx, slog.LevelDebug, "qonto request", attrs...)
+	}
+}
+
+// logQuery encodes the query with the IBANs masked.
+func logQuery(query url.Values) string {
+	masked := make(url.Values, len(query))
+	for k, values := range query {
+		for _, v := range values {
+			if strings.EqualFold(k, "iban") {
+				v = MaskIBAN(v)
+			}
+			masked.Add(k, v)
+		}
+	}
+	s, _ := url.QueryUnescape(masked.Encode())
+	return s
+}
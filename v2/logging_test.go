@@ -0,0 +1,92 @@
+package qonto_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file.pdf" {
+			fmt.Fprint(w, "PDF")
+			return
+		}
+		fmt.Fprint(w, `{"transactions":[],"meta":{}}`)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := qonto.NewClient("acme", "top-secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	page := 2
+	if _, err := c.GetTransactions("acme-account", "FR7630001007941234567890185", &qonto.GetTransactionOptions{CurrentPage: &page}); err != nil {
+		t.Fatalf("c.GetTransactions() failed: %v", err)
+	}
+	a := &qonto.Attachment{URL: srv.URL + "/file.pdf?X-Amz-Credential=AKIA&X-Amz-Signature=abcdef"}
+	if _, err := c.DownloadAttachment(a); err != nil {
+		t.Fatalf("c.DownloadAttachment() failed: %v", err)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{"method=GET", "path=/transactions", "page=2", "iban=FR76*******************0185", "status=200", "attempt=1", "path=/file.pdf"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs do not contain %q:\n%s", want, logs)
+		}
+	}
+	for _, secret := range []string{"top-secret", "FR7630001007941234567890185", "abcdef", "AKIA"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("logs contain %q:\n%s", secret, logs)
+		}
+	}
+}
+
+func TestLogger_TransportFailure(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	closed := srv.URL
+	srv.Close()
+
+	var buf bytes.Buffer
+	c := qonto.NewClient("acme", "top-secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", closed, nil)
+	c.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if _, err := c.GetTransactions("acme-account", "FR7630001007941234567890185", nil); err == nil {
+		t.Fatal("c.GetTransactions() did not fail")
+	}
+	a := &qonto.Attachment{URL: closed + "/file.pdf?X-Amz-Credential=AKIA&X-Amz-Signature=deadbeefsecret"}
+	if _, err := c.DownloadAttachment(a); err == nil {
+		t.Fatal("c.DownloadAttachment() did not fail")
+	}
+
+	logs := buf.String()
+	if strings.Count(logs, "qonto request failed") != 2 || !strings.Contains(logs, "connection refused") {
+		t.Errorf("logs do not report the failures:\n%s", logs)
+	}
+	for _, secret := range []string{"FR7630001007941234567890185", "deadbeefsecret", "AKIA"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("logs contain %q:\n%s", secret, logs)
+		}
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := fmt.Errorf("GET %s returned %d", "https://example.com/transactions?iban=FR76+3000+1007+9412+3456+7890+185&slug=acme", 500)
+	if got, want := qonto.RedactError(err), "GET https://example.com/transactions?iban=FR76*******************0185&slug=acme returned 500"; got != want {
+		t.Errorf("qonto.RedactError() == %q; want %q", got, want)
+	}
+}
+
+func TestMaskIBAN(t *testing.T) {
+	if got := qonto.MaskIBAN("FR76 3000 1007 9412 3456 7890 185"); got != "FR76*******************0185" {
+		t.Errorf("qonto.MaskIBAN() == %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Package match pairs Qonto transactions with external documents: receipts waiting to be
+attached (MatchReceipts) and invoices waiting to be paid (Reconcile).
+
+Matching is never certain, so the functions return scored proposals meant to be reviewed
+before being acted upon.
+
+Example:
+
+	docs, _ := match.LoadDocuments("./receipts")
+	for _, m := range match.MatchReceipts(docs, transactions, match.ReceiptOptions{}) {
+		if best := m.Best(); best != nil && best.Score > 0.9 {
+			fmt.Printf("%s ➡︎ %s (%.2f)\n", m.Document.Path, best.Transaction.ID, best.Score)
+		}
+	}
+*/
+package match
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize lowercases s, and replaces everything but letters and digits by single spaces.
+func Normalize(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Similarity returns a score between 0 (nothing in common) and 1 (same text) for two labels.
+//
+// It computes the Dice coefficient on the character bigrams of the normalized strings, which
+// is tolerant to small typos and to the extra words banks add to labels.
+func Similarity(a, b string) float64 {
+	a, b = Normalize(a), Normalize(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(ba))
+	for _, g := range ba {
+		counts[g]++
+	}
+	common := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(ba)+len(bb))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return []string{s}
+	}
+	res := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		res = append(res, string(runes[i:i+2]))
+	}
+	return res
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
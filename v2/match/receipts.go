@@ -0,0 +1,279 @@
+package match
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// Default values for ReceiptOptions.
+const (
+	DefaultDateWindow      = 7 * 24 * time.Hour
+	DefaultAmountTolerance = 0.02
+)
+
+// Document describes a receipt. Zero fields are unknown.
+type Document struct {
+	Path        string    `json:"-"`
+	AmountCents int64     `json:"amount_cents,omitempty"`
+	Date        time.Time `json:"date,omitempty"`
+	Vendor      string    `json:"vendor,omitempty"`
+}
+
+// Uploader attaches files to transactions. It is implemented by *qonto.Client.
+type Uploader interface {
+	UploadAttachmentContext(ctx context.Context, transactionID, filename string, content io.Reader) error
+}
+
+// ReceiptOptions configures MatchReceipts.
+type ReceiptOptions struct {
+	// DateWindow is the maximum distance between the document and the transaction (defaults to DefaultDateWindow)
+	DateWindow time.Duration
+	// AmountTolerance is the maximum relative difference of amounts, for foreign currencies or tips (defaults to DefaultAmountTolerance)
+	AmountTolerance float64
+	// MaxProposals limits the number of proposals per document (0 for no limit)
+	MaxProposals int
+}
+
+// ReceiptProposal is a transaction that could match a document.
+type ReceiptProposal struct {
+	Transaction *qonto.Transaction
+	Score       float64 // between 0 and 1
+	AmountScore float64
+	DateScore   float64
+	VendorScore float64
+}
+
+// ReceiptMatch lists the proposals for a single document, best first.
+type ReceiptMatch struct {
+	Document  Document
+	Proposals []ReceiptProposal
+}
+
+// Best returns the best proposal, or nil.
+func (m *ReceiptMatch) Best() *ReceiptProposal {
+	if len(m.Proposals) == 0 {
+		return nil
+	}
+	return &m.Proposals[0]
+}
+
+// weights of the partial scores
+const (
+	amountWeight = 0.5
+	dateWeight   = 0.3
+	vendorWeight = 0.2
+)
+
+// MatchReceipts scores the debit transactions without attachment, declined and reversed ones
+// excepted, against each document using the amount, the distance between the emission date and
+// the document date, and the similarity of the label with the vendor name.
+func MatchReceipts(docs []Document, transactions []*qonto.Transaction, opt ReceiptOptions) []ReceiptMatch {
+	if opt.DateWindow <= 0 {
+		opt.DateWindow = DefaultDateWindow
+	}
+	if opt.AmountTolerance <= 0 {
+		opt.AmountTolerance = DefaultAmountTolerance
+	}
+
+	var candidates []*qonto.Transaction
+	for _, t := range transactions {
+		if t.Side == qonto.TransactionSideDebit && len(t.AttachmentIDs) == 0 &&
+			t.Status != qonto.TransactionStatusDeclined && t.Status != qonto.TransactionStatusReversed {
+			candidates = append(candidates, t)
+		}
+	}
+
+	res := make([]ReceiptMatch, len(docs))
+	for i, d := range docs {
+		res[i].Document = d
+		for _, t := range candidates {
+			p, ok := scoreReceipt(&d, t, &opt)
+			if ok {
+				res[i].Proposals = append(res[i].Proposals, p)
+			}
+		}
+		sort.SliceStable(res[i].Proposals, func(a, b int) bool {
+			return res[i].Proposals[a].Score > res[i].Proposals[b].Score
+		})
+		if opt.MaxProposals > 0 && len(res[i].Proposals) > opt.MaxProposals {
+			res[i].Proposals = res[i].Proposals[:opt.MaxProposals]
+		}
+	}
+	return res
+}
+
+// scoreReceipt scores t against d. Unknown document fields get a neutral score of 0.5,
+// and transactions out of the amount tolerance or date window are rejected.
+func scoreReceipt(d *Document, t *qonto.Transaction, opt *ReceiptOptions) (ReceiptProposal, bool) {
+	p := ReceiptProposal{Transaction: t, AmountScore: 0.5, DateScore: 0.5, VendorScore: 0.5}
+
+	if d.AmountCents != 0 {
+		// the receipt can be in the local currency of the transaction
+		diff := abs(d.AmountCents - t.AmountCents)
+		if t.LocalCurrency != "" && t.LocalCurrency != t.Currency {
+			if ld := abs(d.AmountCents - t.LocalAmountCents); ld < diff {
+				diff = ld
+			}
+		}
+		tolerance := opt.AmountTolerance * float64(d.AmountCents)
+		if float64(diff) > tolerance {
+			return p, false
+		}
+		p.AmountScore = 1
+		if diff > 0 {
+			p.AmountScore = 0.8 * (1 - float64(diff)/tolerance)
+		}
+	}
+	if !d.Date.IsZero() {
+		distance := math.Abs(float64(t.EmittedAt.Sub(d.Date)))
+		if distance > float64(opt.DateWindow) {
+			return p, false
+		}
+		p.DateScore = 1 - distance/float64(opt.DateWindow)
+	}
+	if d.Vendor != "" {
+		label := ""
+		if t.Label != nil {
+			label = *t.Label
+		}
+		p.VendorScore = Similarity(d.Vendor, label)
+	}
+	p.Score = amountWeight*p.AmountScore + dateWeight*p.DateScore + vendorWeight*p.VendorScore
+	return p, true
+}
+
+// filenameDate and filenameAmount match the dates (2006-01-02 or 20060102) and amounts (12.34 or 12,34) in file names.
+var (
+	filenameDate   = regexp.MustCompile(`^(\d{4})-?(\d{2})-?(\d{2})$`)
+	filenameAmount = regexp.MustCompile(`^(\d+)[.,](\d{2})$`)
+)
+
+// documentExtensions are the extensions removed from the file names, others (such as the
+// decimals of "acme_12.00") are part of the name.
+var documentExtensions = map[string]bool{
+	".pdf": true, ".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".heic": true,
+	".tif": true, ".tiff": true, ".webp": true,
+}
+
+// ParseFilename extracts the document details from a file name such as
+// "2020-01-15_SNCF Paris_55.00.pdf": dates, amounts and the remaining words as vendor.
+func ParseFilename(name string) Document {
+	d := Document{Path: name}
+	base := filepath.Base(name)
+	if ext := filepath.Ext(base); documentExtensions[strings.ToLower(ext)] {
+		base = strings.TrimSuffix(base, ext)
+	}
+	var vendor []string
+	for _, part := range strings.FieldsFunc(base, func(r rune) bool { return r == '_' || r == ' ' }) {
+		if m := filenameDate.FindStringSubmatch(part); m != nil && d.Date.IsZero() {
+			if t, err := time.Parse("20060102", m[1]+m[2]+m[3]); err == nil {
+				d.Date = t
+				continue
+			}
+		}
+		if m := filenameAmount.FindStringSubmatch(part); m != nil && d.AmountCents == 0 {
+			units, _ := strconv.ParseInt(m[1], 10, 64)
+			cents, _ := strconv.ParseInt(m[2], 10, 64)
+			d.AmountCents = units*100 + cents
+			continue
+		}
+		vendor = append(vendor, part)
+	}
+	d.Vendor = strings.Join(vendor, " ")
+	return d
+}
+
+// LoadDocuments lists the documents of dir. The details are parsed from the file names, and
+// overridden by the optional sidecar file "<name>.json" (for eg. "receipt.pdf.json").
+func LoadDocuments(dir string) ([]Document, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var docs []Document
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(e.Name(), ".json") || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		d := ParseFilename(path)
+		buf, err := ioutil.ReadFile(path + ".json")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var sidecar Document
+			if err = json.Unmarshal(buf, &sidecar); err != nil {
+				return nil, err
+			}
+			if sidecar.AmountCents != 0 {
+				d.AmountCents = sidecar.AmountCents
+			}
+			if !sidecar.Date.IsZero() {
+				d.Date = sidecar.Date
+			}
+			if sidecar.Vendor != "" {
+				d.Vendor = sidecar.Vendor
+			}
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// UploadBest uploads the documents to their transactions, when the score reaches minScore.
+// A transaction receives at most one document: the pairs are assigned by decreasing score,
+// so a document whose best transaction went to a better match falls back on its next proposal.
+// It returns the uploaded matches, holding the chosen proposal only.
+func UploadBest(ctx context.Context, u Uploader, matches []ReceiptMatch, minScore float64) ([]ReceiptMatch, error) {
+	type pair struct {
+		doc int
+		p   ReceiptProposal
+	}
+	var pairs []pair
+	for i, m := range matches {
+		for _, p := range m.Proposals {
+			if p.Score >= minScore {
+				pairs = append(pairs, pair{doc: i, p: p})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].p.Score > pairs[j].p.Score })
+
+	var uploaded []ReceiptMatch
+	usedDocs := make(map[int]bool)
+	usedTransactions := make(map[string]bool)
+	for _, p := range pairs {
+		if usedDocs[p.doc] || usedTransactions[p.p.Transaction.ID] {
+			continue
+		}
+		d := matches[p.doc].Document
+		if err := uploadFile(ctx, u, p.p.Transaction.ID, d.Path); err != nil {
+			return uploaded, err
+		}
+		usedDocs[p.doc], usedTransactions[p.p.Transaction.ID] = true, true
+		uploaded = append(uploaded, ReceiptMatch{Document: d, Proposals: []ReceiptProposal{p.p}})
+	}
+	return uploaded, nil
+}
+
+func uploadFile(ctx context.Context, u Uploader, transactionID, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return u.UploadAttachmentContext(ctx, transactionID, path, f)
+}
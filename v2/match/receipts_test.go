@@ -0,0 +1,149 @@
+package match_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/match"
+)
+
+func debit(id, label string, cents int64, emittedAt time.Time) *qonto.Transaction {
+	return &qonto.Transaction{ID: id, Label: &label, AmountCents: cents, Side: qonto.TransactionSideDebit, EmittedAt: emittedAt}
+}
+
+func TestParseFilename(t *testing.T) {
+	d := match.ParseFilename("receipts/2020-01-15_SNCF Paris_55,00.pdf")
+	if d.AmountCents != 5500 {
+		t.Errorf("d.AmountCents == %d; want %d", d.AmountCents, 5500)
+	}
+	if want := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC); !d.Date.Equal(want) {
+		t.Errorf("d.Date == %v; want %v", d.Date, want)
+	}
+	if d.Vendor != "SNCF Paris" {
+		t.Errorf("d.Vendor == %q; want %q", d.Vendor, "SNCF Paris")
+	}
+
+	// only the document extensions are removed
+	for name, want := range map[string]int64{"acme_12.00": 1200, "acme_12.00.PDF": 1200, "acme_12.00.jpeg": 1200} {
+		if d := match.ParseFilename(name); d.AmountCents != want || d.Vendor != "acme" {
+			t.Errorf("match.ParseFilename(%q) == %+v; want acme, %d", name, d, want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := match.Similarity("SNCF", "sncf"); s != 1 {
+		t.Errorf("match.Similarity(SNCF, sncf) == %v; want 1", s)
+	}
+	close := match.Similarity("Amazon", "AMAZON EU SARL")
+	far := match.Similarity("Amazon", "SNCF PARIS")
+	if close <= far {
+		t.Errorf("match.Similarity() == %v for close labels, %v for different labels", close, far)
+	}
+}
+
+func TestMatchReceipts(t *testing.T) {
+	day := time.Date(2020, 1, 15, 10, 0, 0, 0, time.UTC)
+	attached := debit("attached", "SNCF", 5500, day)
+	attached.AttachmentIDs = []string{"a1"}
+	declined := debit("declined", "SNCF PARIS", 5500, day)
+	declined.Status = qonto.TransactionStatusDeclined
+	reversed := debit("reversed", "SNCF PARIS", 5500, day)
+	reversed.Status = qonto.TransactionStatusReversed
+	transactions := []*qonto.Transaction{
+		declined,
+		reversed,
+		debit("sncf", "SNCF PARIS", 5500, day),
+		debit("other", "RESTAURANT", 5500, day.AddDate(0, 0, 3)),
+		debit("far", "SNCF PARIS", 5500, day.AddDate(0, 1, 0)),
+		debit("amount", "SNCF PARIS", 9900, day),
+		attached,
+	}
+
+	docs := []match.Document{{Path: "receipt.pdf", AmountCents: 5500, Date: day.Truncate(24 * time.Hour), Vendor: "sncf"}}
+	matches := match.MatchReceipts(docs, transactions, match.ReceiptOptions{})
+	if len(matches) != 1 {
+		t.Fatalf("len(matches) == %d; want %d", len(matches), 1)
+	}
+	proposals := matches[0].Proposals
+	if len(proposals) != 2 || proposals[0].Transaction.ID != "sncf" || proposals[1].Transaction.ID != "other" {
+		t.Fatalf("proposals == %+v; want sncf then other", proposals)
+	}
+	if proposals[0].Score <= proposals[1].Score {
+		t.Errorf("proposals are not sorted by score")
+	}
+}
+
+// fakeUploader records the uploaded files.
+type fakeUploader map[string]string
+
+func (u fakeUploader) UploadAttachmentContext(ctx context.Context, transactionID, filename string, content io.Reader) error {
+	buf, err := ioutil.ReadAll(content)
+	u[transactionID] = string(buf)
+	return err
+}
+
+func TestUploadBest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "receipt.pdf")
+	if err := ioutil.WriteFile(path, []byte("PDF"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path+".json", []byte(`{"amount_cents": 1200, "vendor": "Coffee"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := match.LoadDocuments(dir)
+	if err != nil {
+		t.Fatalf("match.LoadDocuments() failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].AmountCents != 1200 || docs[0].Vendor != "Coffee" {
+		t.Fatalf("docs == %+v; want the details of the sidecar file", docs)
+	}
+
+	matches := match.MatchReceipts(docs, []*qonto.Transaction{debit("t1", "COFFEE", 1200, time.Now())}, match.ReceiptOptions{})
+	u := fakeUploader{}
+	uploaded, err := match.UploadBest(context.Background(), u, matches, 0.5)
+	if err != nil {
+		t.Fatalf("match.UploadBest() failed: %v", err)
+	}
+	if len(uploaded) != 1 || u["t1"] != "PDF" {
+		t.Errorf("uploads == %v; want receipt.pdf uploaded to t1", u)
+	}
+}
+
+func TestUploadBest_Competing(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2020, 1, 15, 10, 0, 0, 0, time.UTC)
+	docs := []match.Document{
+		// the weaker match of "exact" comes first
+		{Path: filepath.Join(dir, "weak.pdf"), AmountCents: 5500, Date: day.AddDate(0, 0, -2), Vendor: "SNCF"},
+		{Path: filepath.Join(dir, "strong.pdf"), AmountCents: 5500, Date: day, Vendor: "SNCF"},
+	}
+	for _, d := range docs {
+		if err := ioutil.WriteFile(d.Path, []byte(filepath.Base(d.Path)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transactions := []*qonto.Transaction{
+		debit("exact", "SNCF", 5500, day),
+		debit("earlier", "SNCF", 5500, day.AddDate(0, 0, -6)),
+	}
+
+	matches := match.MatchReceipts(docs, transactions, match.ReceiptOptions{})
+	if matches[0].Best().Transaction.ID != "exact" {
+		t.Fatalf("matches[0].Best() == %s; want exact", matches[0].Best().Transaction.ID)
+	}
+	u := fakeUploader{}
+	uploaded, err := match.UploadBest(context.Background(), u, matches, 0.5)
+	if err != nil {
+		t.Fatalf("match.UploadBest() failed: %v", err)
+	}
+	if len(uploaded) != 2 || u["exact"] != "strong.pdf" || u["earlier"] != "weak.pdf" {
+		t.Errorf("uploads == %v; want strong.pdf to exact, and weak.pdf to earlier", u)
+	}
+}
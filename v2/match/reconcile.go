@@ -0,0 +1,503 @@
+package match
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// Default values for ReconcileOptions.
+const (
+	DefaultPaymentWindow         = 30 * 24 * time.Hour
+	DefaultMaxGroupSize          = 3
+	DefaultMinCustomerSimilarity = 0.5
+)
+
+// Invoice is an open invoice, issued outside of Qonto.
+type Invoice struct {
+	Number      string
+	AmountCents int64
+	Customer    string
+	DueDate     time.Time
+}
+
+// ReconcileOptions configures Reconcile.
+type ReconcileOptions struct {
+	// PaymentWindow is the maximum distance between the due date and the payment, when
+	// matching on amounts (defaults to DefaultPaymentWindow)
+	PaymentWindow time.Duration
+	// MaxGroupSize is the maximum number of invoices paid at once, or of payments for a
+	// single invoice, when matching on amounts (defaults to DefaultMaxGroupSize)
+	MaxGroupSize int
+	// MinCustomerSimilarity is the minimum similarity between the customer and the label to
+	// group invoices or payments by amount (defaults to DefaultMinCustomerSimilarity)
+	MinCustomerSimilarity float64
+}
+
+// ReconciliationKind describes the shape of a Reconciliation.
+type ReconciliationKind string
+
+const (
+	// ReconciliationExact is an invoice paid by a single transaction
+	ReconciliationExact ReconciliationKind = "exact"
+	// ReconciliationGrouped are several invoices paid by a single transaction
+	ReconciliationGrouped ReconciliationKind = "grouped"
+	// ReconciliationSplit is an invoice paid in several transactions
+	ReconciliationSplit ReconciliationKind = "split"
+	// ReconciliationPartial is an invoice not fully paid (or overpaid), see RemainingCents. Partial
+	// payments are matched by reference, or by amount for the credits of the same customer below
+	// the invoice total
+	ReconciliationPartial ReconciliationKind = "partial"
+)
+
+// Reconciliation pairs invoices with the credits paying them.
+type Reconciliation struct {
+	Kind         ReconciliationKind
+	Invoices     []Invoice
+	Transactions []*qonto.Transaction
+	// ByReference is true when the invoice numbers were found in the labels or notes,
+	// and false for matches on amounts only
+	ByReference bool
+	// Score is between 0 and 1, and is 1 for matches by reference
+	Score float64
+	// RemainingCents is the amount still due (negative when overpaid)
+	RemainingCents int64
+}
+
+func (r *Reconciliation) String() string {
+	numbers := make([]string, len(r.Invoices))
+	for i, inv := range r.Invoices {
+		numbers[i] = inv.Number
+	}
+	ids := make([]string, len(r.Transactions))
+	for i, t := range r.Transactions {
+		ids[i] = t.ID
+	}
+	by := "amount"
+	if r.ByReference {
+		by = "reference"
+	}
+	return fmt.Sprintf("%s ➡︎ %s: %s by %s (score=%.2f remaining=%d)",
+		strings.Join(numbers, ","), strings.Join(ids, ","), r.Kind, by, r.Score, r.RemainingCents)
+}
+
+// ReconcileResult lists the reconciliations, and what is left to review by hand.
+type ReconcileResult struct {
+	Reconciliations  []Reconciliation
+	UnpaidInvoices   []Invoice
+	UnmatchedCredits []*qonto.Transaction
+}
+
+// Reconcile matches the invoices with the credit transactions.
+//
+// Invoices are first matched on their numbers, searched in the labels and notes of the
+// transactions, whatever the dates. The remaining invoices are then matched on amounts,
+// within the payment window: one invoice for one credit, then several invoices of the same
+// customer for one credit, several credits from the same customer for one invoice, and finally
+// the credits from the same customer paying a part of an invoice.
+// An invoice belongs to a single Reconciliation. Declined and reversed transactions are ignored.
+func Reconcile(invoices []Invoice, transactions []*qonto.Transaction, opt ReconcileOptions) *ReconcileResult {
+	if opt.PaymentWindow <= 0 {
+		opt.PaymentWindow = DefaultPaymentWindow
+	}
+	if opt.MaxGroupSize <= 0 {
+		opt.MaxGroupSize = DefaultMaxGroupSize
+	}
+	if opt.MinCustomerSimilarity <= 0 {
+		opt.MinCustomerSimilarity = DefaultMinCustomerSimilarity
+	}
+
+	r := &reconciler{
+		opt:             &opt,
+		invoices:        invoices,
+		invoiceMatched:  make([]bool, len(invoices)),
+		creditMatched:   make(map[string]bool),
+		result:          &ReconcileResult{},
+		normalizedCache: make(map[string]string),
+	}
+	for _, t := range transactions {
+		if t.Side == qonto.TransactionSideCredit && t.Status != qonto.TransactionStatusDeclined && t.Status != qonto.TransactionStatusReversed {
+			r.credits = append(r.credits, t)
+		}
+	}
+	sort.SliceStable(r.credits, func(i, j int) bool { return r.credits[i].EmittedAt.Before(r.credits[j].EmittedAt) })
+
+	r.matchReferences()
+	r.matchAmounts()
+	r.matchGroupedAmounts()
+	r.matchSplitAmounts()
+	r.matchPartialAmounts()
+
+	for i, inv := range invoices {
+		if !r.invoiceMatched[i] {
+			r.result.UnpaidInvoices = append(r.result.UnpaidInvoices, inv)
+		}
+	}
+	for _, t := range r.credits {
+		if !r.creditMatched[t.ID] {
+			r.result.UnmatchedCredits = append(r.result.UnmatchedCredits, t)
+		}
+	}
+	return r.result
+}
+
+// reconciler holds the state of Reconcile.
+type reconciler struct {
+	opt             *ReconcileOptions
+	invoices        []Invoice
+	credits         []*qonto.Transaction // sorted by date
+	invoiceMatched  []bool
+	creditMatched   map[string]bool
+	result          *ReconcileResult
+	normalizedCache map[string]string
+}
+
+// referenceGroup holds invoices and the credits paying them, found by reference.
+type referenceGroup struct {
+	invoices []int
+	payments []*qonto.Transaction
+}
+
+// matchReferences matches the credits mentioning invoice numbers. A credit citing several
+// invoices pays what remains due on each of them: the invoices partially paid by earlier
+// credits are reconciled together with all their payments, and the ones already fully paid
+// are left out.
+func (r *reconciler) matchReferences() {
+	var groups []*referenceGroup
+	byInvoice := make(map[int]*referenceGroup)
+	for _, t := range r.credits {
+		refs := r.references(t)
+		if len(refs) > 1 {
+			unpaid := refs[:0]
+			for _, i := range refs {
+				if g := byInvoice[i]; g == nil || r.remaining(g) > 0 {
+					unpaid = append(unpaid, i)
+				}
+			}
+			refs = unpaid
+		}
+		if len(refs) == 0 {
+			continue
+		}
+
+		// the credit joins the group of the first invoice, and the other groups are merged in
+		target := byInvoice[refs[0]]
+		if target == nil {
+			target = &referenceGroup{}
+			groups = append(groups, target)
+		}
+		for _, i := range refs {
+			switch g := byInvoice[i]; {
+			case g == nil:
+				target.invoices = append(target.invoices, i)
+				byInvoice[i] = target
+			case g != target:
+				target.invoices = append(target.invoices, g.invoices...)
+				target.payments = append(target.payments, g.payments...)
+				for _, j := range g.invoices {
+					byInvoice[j] = target
+				}
+				g.invoices, g.payments = nil, nil
+			}
+		}
+		target.payments = append(target.payments, t)
+		r.creditMatched[t.ID] = true
+	}
+
+	// the payments of several invoices come first
+	for _, grouped := range []bool{true, false} {
+		for _, g := range groups {
+			if len(g.invoices) == 0 || (len(g.invoices) > 1) != grouped {
+				continue
+			}
+			invoices := make([]Invoice, len(g.invoices))
+			for k, i := range g.invoices {
+				invoices[k] = r.invoices[i]
+				r.invoiceMatched[i] = true
+			}
+			sort.SliceStable(g.payments, func(a, b int) bool { return g.payments[a].EmittedAt.Before(g.payments[b].EmittedAt) })
+			r.add(invoices, g.payments, true, 1)
+		}
+	}
+}
+
+// remaining returns the amount still due on the invoices of g.
+func (r *reconciler) remaining(g *referenceGroup) int64 {
+	var remaining int64
+	for _, i := range g.invoices {
+		remaining += r.invoices[i].AmountCents
+	}
+	for _, t := range g.payments {
+		remaining -= t.AmountCents
+	}
+	return remaining
+}
+
+// minReferenceLength is the minimum number of letters and digits of the invoice numbers
+// searched in the transactions: shorter numbers (such as "12") are matched by amount only.
+const minReferenceLength = 4
+
+// references returns the indexes of the unmatched invoices mentioned by t. The numbers are
+// searched as whole words in the label and in the note, separately.
+func (r *reconciler) references(t *qonto.Transaction) []int {
+	var texts []string
+	for _, s := range []*string{t.Label, t.Note} {
+		if s != nil {
+			texts = append(texts, " "+Normalize(*s)+" ")
+		}
+	}
+	var refs []int
+	for i, inv := range r.invoices {
+		if r.invoiceMatched[i] {
+			continue
+		}
+		number := r.normalize(inv.Number)
+		if utf8.RuneCountInString(strings.Replace(number, " ", "", -1)) < minReferenceLength {
+			continue
+		}
+		for _, text := range texts {
+			if strings.Contains(text, " "+number+" ") {
+				refs = append(refs, i)
+				break
+			}
+		}
+	}
+	return refs
+}
+
+// matchAmounts matches single invoices with single credits of the same amount, preferring
+// the closest payments from the most similar customers.
+func (r *reconciler) matchAmounts() {
+	for i, inv := range r.invoices {
+		if r.invoiceMatched[i] {
+			continue
+		}
+		var best *qonto.Transaction
+		bestScore := 0.0
+		for _, t := range r.credits {
+			if r.creditMatched[t.ID] || t.AmountCents != inv.AmountCents {
+				continue
+			}
+			score, ok := r.score(&inv, t)
+			if ok && score > bestScore {
+				best, bestScore = t, score
+			}
+		}
+		if best != nil {
+			r.invoiceMatched[i] = true
+			r.creditMatched[best.ID] = true
+			r.add([]Invoice{inv}, []*qonto.Transaction{best}, false, bestScore)
+		}
+	}
+}
+
+// matchGroupedAmounts matches single credits with several invoices of the same customer.
+func (r *reconciler) matchGroupedAmounts() {
+	for _, t := range r.credits {
+		if r.creditMatched[t.ID] {
+			continue
+		}
+		var candidates []int
+		var amounts []int64
+		total := 0.0
+		for i, inv := range r.invoices {
+			if r.invoiceMatched[i] || r.similarity(inv.Customer, t) < r.opt.MinCustomerSimilarity {
+				continue
+			}
+			if _, ok := r.score(&inv, t); ok {
+				candidates = append(candidates, i)
+				amounts = append(amounts, inv.AmountCents)
+			}
+		}
+		subset := findSubset(amounts, t.AmountCents, r.opt.MaxGroupSize)
+		if len(subset) < 2 {
+			continue
+		}
+		var invoices []Invoice
+		for _, k := range subset {
+			i := candidates[k]
+			r.invoiceMatched[i] = true
+			invoices = append(invoices, r.invoices[i])
+			score, _ := r.score(&r.invoices[i], t)
+			total += score
+		}
+		r.creditMatched[t.ID] = true
+		r.add(invoices, []*qonto.Transaction{t}, false, total/float64(len(subset)))
+	}
+}
+
+// matchSplitAmounts matches single invoices with several credits from the same customer.
+func (r *reconciler) matchSplitAmounts() {
+	for i, inv := range r.invoices {
+		if r.invoiceMatched[i] {
+			continue
+		}
+		var candidates []*qonto.Transaction
+		var amounts []int64
+		for _, t := range r.credits {
+			if r.creditMatched[t.ID] || r.similarity(inv.Customer, t) < r.opt.MinCustomerSimilarity {
+				continue
+			}
+			if _, ok := r.score(&inv, t); ok {
+				candidates = append(candidates, t)
+				amounts = append(amounts, t.AmountCents)
+			}
+		}
+		subset := findSubset(amounts, inv.AmountCents, r.opt.MaxGroupSize)
+		if len(subset) < 2 {
+			continue
+		}
+		var payments []*qonto.Transaction
+		total := 0.0
+		for _, k := range subset {
+			t := candidates[k]
+			r.creditMatched[t.ID] = true
+			payments = append(payments, t)
+			score, _ := r.score(&inv, t)
+			total += score
+		}
+		r.invoiceMatched[i] = true
+		r.add([]Invoice{inv}, payments, false, total/float64(len(subset)))
+	}
+}
+
+// matchPartialAmounts matches single invoices with the credits from the same customer whose
+// total is below the invoice amount, taken by date up to MaxGroupSize credits.
+func (r *reconciler) matchPartialAmounts() {
+	for i, inv := range r.invoices {
+		if r.invoiceMatched[i] || inv.Customer == "" {
+			continue
+		}
+		var payments []*qonto.Transaction
+		var paid int64
+		total := 0.0
+		for _, t := range r.credits {
+			if len(payments) == r.opt.MaxGroupSize {
+				break
+			}
+			if r.creditMatched[t.ID] || t.AmountCents <= 0 || paid+t.AmountCents >= inv.AmountCents ||
+				r.similarity(inv.Customer, t) < r.opt.MinCustomerSimilarity {
+				continue
+			}
+			if score, ok := r.score(&inv, t); ok {
+				payments = append(payments, t)
+				paid += t.AmountCents
+				total += score
+			}
+		}
+		if len(payments) == 0 {
+			continue
+		}
+		for _, t := range payments {
+			r.creditMatched[t.ID] = true
+		}
+		r.invoiceMatched[i] = true
+		// the missing amount makes the match less certain
+		r.add([]Invoice{inv}, payments, false, total/float64(len(payments))*float64(paid)/float64(inv.AmountCents))
+	}
+}
+
+// score rates a payment of inv by t, on amounts only. It returns false when t is out of the
+// payment window.
+func (r *reconciler) score(inv *Invoice, t *qonto.Transaction) (float64, bool) {
+	dateScore := 0.5
+	if !inv.DueDate.IsZero() {
+		distance := math.Abs(float64(t.EmittedAt.Sub(inv.DueDate)))
+		if distance > float64(r.opt.PaymentWindow) {
+			return 0, false
+		}
+		dateScore = 1 - distance/float64(r.opt.PaymentWindow)
+	}
+	customerScore := 0.5
+	if inv.Customer != "" {
+		customerScore = r.similarity(inv.Customer, t)
+	}
+	// amounts are always equal here, but are not enough on their own
+	return 0.4 + 0.3*dateScore + 0.3*customerScore, true
+}
+
+func (r *reconciler) similarity(customer string, t *qonto.Transaction) float64 {
+	if customer == "" || t.Label == nil {
+		return 0
+	}
+	return Similarity(customer, *t.Label)
+}
+
+// normalize caches the normalized invoice numbers.
+func (r *reconciler) normalize(s string) string {
+	n, ok := r.normalizedCache[s]
+	if !ok {
+		n = Normalize(s)
+		r.normalizedCache[s] = n
+	}
+	return n
+}
+
+// add records a reconciliation.
+func (r *reconciler) add(invoices []Invoice, transactions []*qonto.Transaction, byReference bool, score float64) {
+	var remaining int64
+	for _, inv := range invoices {
+		remaining += inv.AmountCents
+	}
+	for _, t := range transactions {
+		remaining -= t.AmountCents
+	}
+	kind := ReconciliationExact
+	switch {
+	case remaining != 0:
+		kind = ReconciliationPartial
+	case len(invoices) > 1:
+		kind = ReconciliationGrouped
+	case len(transactions) > 1:
+		kind = ReconciliationSplit
+	}
+	r.result.Reconciliations = append(r.result.Reconciliations, Reconciliation{
+		Kind:           kind,
+		Invoices:       invoices,
+		Transactions:   transactions,
+		ByReference:    byReference,
+		Score:          score,
+		RemainingCents: remaining,
+	})
+}
+
+// maxSubsetCandidates bounds the search in findSubset.
+const maxSubsetCandidates = 20
+
+// findSubset returns the indexes of at most maxSize amounts summing to target, or nil.
+// Only the first maxSubsetCandidates amounts are considered.
+func findSubset(amounts []int64, target int64, maxSize int) []int {
+	if len(amounts) > maxSubsetCandidates {
+		amounts = amounts[:maxSubsetCandidates]
+	}
+	var indexes []int
+	var search func(start int, remaining int64) bool
+	search = func(start int, remaining int64) bool {
+		if remaining == 0 && len(indexes) > 0 {
+			return true
+		}
+		if len(indexes) == maxSize {
+			return false
+		}
+		for i := start; i < len(amounts); i++ {
+			if amounts[i] <= 0 || amounts[i] > remaining {
+				continue
+			}
+			indexes = append(indexes, i)
+			if search(i+1, remaining-amounts[i]) {
+				return true
+			}
+			indexes = indexes[:len(indexes)-1]
+		}
+		return false
+	}
+	if target <= 0 || !search(0, target) {
+		return nil
+	}
+	return indexes
+}
@@ -0,0 +1,171 @@
+package match_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/match"
+)
+
+func credit(id, label string, cents int64, emittedAt time.Time) *qonto.Transaction {
+	return &qonto.Transaction{ID: id, Label: &label, AmountCents: cents, Side: qonto.TransactionSideCredit, EmittedAt: emittedAt}
+}
+
+func TestReconcile(t *testing.T) {
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []match.Invoice{
+		{Number: "F-001", AmountCents: 10000, Customer: "ACME", DueDate: due},
+		{Number: "F-002", AmountCents: 20000, Customer: "ACME", DueDate: due},
+		{Number: "F-003", AmountCents: 30000, Customer: "Globex", DueDate: due},
+		{Number: "F-004", AmountCents: 5000, Customer: "Initech", DueDate: due},
+		{Number: "F-005", AmountCents: 7000, Customer: "Initech", DueDate: due},
+		{Number: "F-006", AmountCents: 40000, Customer: "Umbrella", DueDate: due},
+		{Number: "F-007", AmountCents: 99900, Customer: "Nobody", DueDate: due},
+	}
+	declined := credit("declined", "NOBODY", 99900, due)
+	declined.Status = qonto.TransactionStatusDeclined
+	transactions := []*qonto.Transaction{
+		credit("acme", "VIR ACME F-001 F-002", 30000, due.AddDate(0, 2, 0)), // late, but referenced
+		credit("globex1", "GLOBEX", 10000, due),
+		credit("globex2", "GLOBEX F 003", 15000, due),
+		credit("initech", "INITECH SARL", 12000, due.AddDate(0, 0, 5)),
+		credit("umbrella", "UMBRELLA CORP", 40000, due.AddDate(0, 0, -3)),
+		credit("unknown", "SOMEONE", 1234, due),
+		declined,
+		debit("debit", "UMBRELLA CORP", 40000, due),
+	}
+
+	res := match.Reconcile(invoices, transactions, match.ReconcileOptions{})
+
+	want := []string{
+		"F-001,F-002 ➡︎ acme: grouped by reference (score=1.00 remaining=0)",
+		"F-003 ➡︎ globex2: partial by reference (score=1.00 remaining=15000)",
+		"F-006 ➡︎ umbrella: exact by amount (score=0.89 remaining=0)",
+		"F-004,F-005 ➡︎ initech: grouped by amount (score=0.86 remaining=0)",
+	}
+	if len(res.Reconciliations) != len(want) {
+		t.Fatalf("res.Reconciliations == %v; want %v", res.Reconciliations, want)
+	}
+	for i := range want {
+		if got := res.Reconciliations[i].String(); got != want[i] {
+			t.Errorf("res.Reconciliations[%d] == %s; want %s", i, got, want[i])
+		}
+	}
+	if len(res.UnpaidInvoices) != 1 || res.UnpaidInvoices[0].Number != "F-007" {
+		t.Errorf("res.UnpaidInvoices == %v; want F-007", res.UnpaidInvoices)
+	}
+	if len(res.UnmatchedCredits) != 2 || res.UnmatchedCredits[0].ID != "globex1" || res.UnmatchedCredits[1].ID != "unknown" {
+		t.Errorf("res.UnmatchedCredits == %v; want globex1 and unknown", res.UnmatchedCredits)
+	}
+}
+
+func TestReconcile_Split(t *testing.T) {
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []match.Invoice{{Number: "F-001", AmountCents: 10000, Customer: "ACME", DueDate: due}}
+	transactions := []*qonto.Transaction{
+		credit("t1", "ACME", 6000, due),
+		credit("t2", "OTHER", 4000, due),
+		credit("t3", "ACME", 4000, due.AddDate(0, 0, 10)),
+	}
+
+	res := match.Reconcile(invoices, transactions, match.ReconcileOptions{})
+	if len(res.Reconciliations) != 1 {
+		t.Fatalf("len(res.Reconciliations) == %d; want %d", len(res.Reconciliations), 1)
+	}
+	r := res.Reconciliations[0]
+	if r.Kind != match.ReconciliationSplit || len(r.Transactions) != 2 || r.Transactions[0].ID != "t1" || r.Transactions[1].ID != "t3" {
+		t.Errorf("res.Reconciliations[0] == %s; want a split payment with t1 and t3", &r)
+	}
+}
+
+func TestReconcile_SharedReference(t *testing.T) {
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []match.Invoice{
+		{Number: "F-001", AmountCents: 10000, Customer: "ACME", DueDate: due},
+		{Number: "F-002", AmountCents: 20000, Customer: "ACME", DueDate: due},
+		{Number: "F-003", AmountCents: 30000, Customer: "ACME", DueDate: due},
+	}
+	transactions := []*qonto.Transaction{
+		credit("t1", "ACME F-001", 10000, due),
+		credit("t2", "ACME F-001 F-002 F-003", 50000, due.AddDate(0, 0, 1)),
+	}
+
+	res := match.Reconcile(invoices, transactions, match.ReconcileOptions{})
+	want := []string{
+		"F-002,F-003 ➡︎ t2: grouped by reference (score=1.00 remaining=0)",
+		"F-001 ➡︎ t1: exact by reference (score=1.00 remaining=0)",
+	}
+	if len(res.Reconciliations) != len(want) {
+		t.Fatalf("res.Reconciliations == %v; want %v", res.Reconciliations, want)
+	}
+	for i := range want {
+		if got := res.Reconciliations[i].String(); got != want[i] {
+			t.Errorf("res.Reconciliations[%d] == %s; want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestReconcile_PartiallyPaidReference(t *testing.T) {
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []match.Invoice{
+		{Number: "F-001", AmountCents: 10000, Customer: "ACME", DueDate: due},
+		{Number: "F-002", AmountCents: 20000, Customer: "ACME", DueDate: due},
+	}
+	transactions := []*qonto.Transaction{
+		credit("t1", "ACME F-001", 4000, due),
+		credit("t2", "ACME F-001 F-002", 26000, due.AddDate(0, 0, 1)),
+	}
+
+	// t2 pays the rest of F-001, and F-002
+	res := match.Reconcile(invoices, transactions, match.ReconcileOptions{})
+	want := "F-001,F-002 ➡︎ t1,t2: grouped by reference (score=1.00 remaining=0)"
+	if len(res.Reconciliations) != 1 || res.Reconciliations[0].String() != want {
+		t.Errorf("res.Reconciliations == %v; want %s", res.Reconciliations, want)
+	}
+}
+
+func TestReconcile_ReferenceBoundaries(t *testing.T) {
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []match.Invoice{
+		{Number: "12", AmountCents: 10000, Customer: "ACME", DueDate: due},
+		{Number: "F-2020", AmountCents: 20000, Customer: "Globex", DueDate: due},
+		{Number: "F-3030", AmountCents: 30000, Customer: "Initech", DueDate: due},
+	}
+	note := "3030"
+	split := credit("split", "INITECH F", 30000, due.AddDate(0, 3, 0))
+	split.Note = &note
+	transactions := []*qonto.Transaction{
+		credit("short", "ORDER 12", 5000, due.AddDate(0, 3, 0)),
+		credit("prefix", "GLOBEX F-20201", 20000, due.AddDate(0, 3, 0)),
+		split,
+	}
+
+	// too short, part of a longer number, or split between the label and the note
+	res := match.Reconcile(invoices, transactions, match.ReconcileOptions{})
+	if len(res.Reconciliations) != 0 || len(res.UnpaidInvoices) != 3 {
+		t.Errorf("res.Reconciliations == %v; want none", res.Reconciliations)
+	}
+}
+
+func TestReconcile_PartialAmount(t *testing.T) {
+	due := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	invoices := []match.Invoice{{Number: "F-001", AmountCents: 10000, Customer: "ACME", DueDate: due}}
+	transactions := []*qonto.Transaction{
+		credit("t1", "ACME", 4000, due),
+		credit("t2", "OTHER", 1000, due),
+		credit("t3", "ACME", 12000, due),
+	}
+
+	res := match.Reconcile(invoices, transactions, match.ReconcileOptions{})
+	if len(res.Reconciliations) != 1 {
+		t.Fatalf("res.Reconciliations == %v; want 1 reconciliation", res.Reconciliations)
+	}
+	r := res.Reconciliations[0]
+	if r.Kind != match.ReconciliationPartial || r.ByReference || len(r.Transactions) != 1 || r.Transactions[0].ID != "t1" || r.RemainingCents != 6000 {
+		t.Errorf("res.Reconciliations[0] == %s; want a partial payment by t1", &r)
+	}
+	if len(res.UnmatchedCredits) != 2 {
+		t.Errorf("res.UnmatchedCredits == %v; want t2 and t3", res.UnmatchedCredits)
+	}
+}
@@ -0,0 +1,117 @@
+package qonto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Doer sends HTTP requests. It is implemented by *http.Client.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f.
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer, to change the requests or observe the responses.
+type Middleware func(next Doer) Doer
+
+// RequestIDHeader is the default header of the RequestID middleware.
+const RequestIDHeader = "X-Request-Id"
+
+// doer returns the http.Client of c wrapped in its middlewares, the first one being the outermost.
+func (c *Client) doer() Doer {
+	var d Doer = c.h
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		d = c.Middlewares[i](d)
+	}
+	return d
+}
+
+// UserAgent sets the User-Agent header of the requests.
+func UserAgent(userAgent string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("User-Agent", userAgent)
+			return next.Do(req)
+		})
+	}
+}
+
+// RequestID sets a random identifier in the RequestIDHeader of the requests, unless already set.
+func RequestID() Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(RequestIDHeader) == "" {
+				buf := make([]byte, 16)
+				if _, err := rand.Read(buf); err != nil {
+					return nil, err
+				}
+				req.Header.Set(RequestIDHeader, hex.EncodeToString(buf))
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// redactions hide the credentials in the dumps: authentication headers, and the signatures of
+// the pre-signed attachment URLs.
+var redactions = []*regexp.Regexp{
+	regexp.MustCompile(`(?mi)^((?:Authorization|Proxy-Authorization|Cookie|Set-Cookie|` + StagingTokenHeader + `):[ \t]*)[^\r\n]*`),
+	regexp.MustCompile(`(?i)([?&](?:X-Amz-Signature|X-Amz-Credential|X-Amz-Security-Token|Signature|X-Goog-Signature|sig)=)[^&\s"]+`),
+	regexp.MustCompile(`("(?:access_token|refresh_token|client_secret)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`(?m)((?:^|&)(?:code|code_verifier|refresh_token|client_secret)=)[^&\s]+`),
+}
+
+// Redact hides the credentials in a dump of HTTP requests or responses.
+func Redact(dump string) string {
+	for _, re := range redactions {
+		dump = re.ReplaceAllString(dump, "${1}REDACTED")
+	}
+	return dump
+}
+
+// Debug writes the requests and responses to w, with the credentials redacted and the IBANs of
+// the URLs masked. The bodies are written only for JSON and form contents, so attachments are
+// skipped.
+func Debug(w io.Writer) Middleware {
+	var mu sync.Mutex
+	write := func(dump []byte) {
+		mu.Lock()
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(Redact(maskIBANs(string(dump)))))
+		mu.Unlock()
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if dump, err := httputil.DumpRequestOut(req, isText(req.Header)); err == nil {
+				write(dump)
+			}
+			res, err := next.Do(req)
+			if err != nil {
+				write([]byte(fmt.Sprintf("%s %s failed: %s", req.Method, req.URL, RedactError(err))))
+				return res, err
+			}
+			if dump, err := httputil.DumpResponse(res, isText(res.Header)); err == nil {
+				write(dump)
+			}
+			return res, nil
+		})
+	}
+}
+
+func isText(h http.Header) bool {
+	ct := h.Get("Content-Type")
+	return strings.Contains(ct, "json") || strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "text/")
+}
@@ -0,0 +1,93 @@
+package qonto_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestMiddlewares(t *testing.T) {
+	var userAgent, requestID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, requestID = r.Header.Get("User-Agent"), r.Header.Get(qonto.RequestIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"organization":{"slug":"acme"}}`)
+	}))
+	defer srv.Close()
+
+	var order []string
+	trace := func(name string) qonto.Middleware {
+		return func(next qonto.Doer) qonto.Doer {
+			return qonto.DoerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.Do(req)
+			})
+		}
+	}
+	var debug bytes.Buffer
+	c := qonto.NewClient("acme", "top-secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Middlewares = []qonto.Middleware{trace("first"), qonto.UserAgent("test/1.0"), qonto.RequestID(), qonto.Debug(&debug), trace("last")}
+	if _, err := c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed: %v", err)
+	}
+
+	if userAgent != "test/1.0" {
+		t.Errorf("User-Agent == %q; want %q", userAgent, "test/1.0")
+	}
+	if len(requestID) != 32 {
+		t.Errorf("%s == %q; want a random ID", qonto.RequestIDHeader, requestID)
+	}
+	if strings.Join(order, ",") != "first,last" {
+		t.Errorf("middlewares called in order %v; want [first last]", order)
+	}
+	dump := debug.String()
+	if strings.Contains(dump, "top-secret") || !strings.Contains(dump, "Authorization: REDACTED") || !strings.Contains(dump, `"slug":"acme"`) {
+		t.Errorf("debug dump ==\n%s", dump)
+	}
+}
+
+func TestDebug_IBAN(t *testing.T) {
+	const iban = "FR7612345678901234567890189"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"transactions":[],"meta":{}}`)
+	}))
+
+	var debug bytes.Buffer
+	c := qonto.NewClient("acme", "top-secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Middlewares = []qonto.Middleware{qonto.Debug(&debug)}
+	if _, err := c.GetTransactions("acme-account", iban, nil); err != nil {
+		t.Fatalf("c.GetTransactions() failed: %v", err)
+	}
+	// the failed requests are dumped too
+	srv.Close()
+	if _, err := c.GetTransactions("acme-account", iban, nil); err == nil {
+		t.Fatalf("c.GetTransactions() succeeded with the server closed")
+	}
+
+	dump := debug.String()
+	if strings.Contains(dump, iban) || !strings.Contains(dump, "iban="+qonto.MaskIBAN(iban)) || !strings.Contains(dump, "failed:") {
+		t.Errorf("debug dump ==\n%s", dump)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	in := "GET https://bucket.s3.amazonaws.com/file.pdf?X-Amz-Credential=AKIA&X-Amz-Date=20200101&X-Amz-Signature=abcdef HTTP/1.1\r\n" +
+		"Authorization: slug:secret\r\n" +
+		"\r\n" +
+		`{"access_token":"at","expires_in":3600}`
+	want := "GET https://bucket.s3.amazonaws.com/file.pdf?X-Amz-Credential=REDACTED&X-Amz-Date=20200101&X-Amz-Signature=REDACTED HTTP/1.1\r\n" +
+		"Authorization: REDACTED\r\n" +
+		"\r\n" +
+		`{"access_token":"REDACTED","expires_in":3600}`
+	if got := qonto.Redact(in); got != want {
+		t.Errorf("qonto.Redact() ==\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,340 @@
+package qonto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The OAuth 2.0 endpoints of Qonto. They can be overridden in OAuthConfig.
+var (
+	OAuthAuthURL  = "https://oauth.qonto.com/oauth2/auth"
+	OAuthTokenURL = "https://oauth.qonto.com/oauth2/token"
+)
+
+// OAuth scopes.
+const (
+	ScopeOfflineAccess    = "offline_access" // ⬅︎ needed to receive a refresh token
+	ScopeOrganizationRead = "organization.read"
+	ScopeTransactionRead  = "transaction.read"
+	ScopeTransactionWrite = "transaction.write"
+	ScopeAttachmentRead   = "attachment.read"
+	ScopeAttachmentWrite  = "attachment.write"
+	ScopeMembershipRead   = "membership.read"
+)
+
+// tokenExpiryDelta renews the tokens a bit before they expire.
+const tokenExpiryDelta = 30 * time.Second
+
+// ErrNoToken is returned when the TokenStore holds no token: the user must authorize the application first.
+var ErrNoToken = errors.New("No OAuth token, the authorization code flow must be completed first")
+
+// OAuthError is returned by the token endpoint.
+type OAuthError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("OAuth error %q (%d): %s", e.Code, e.StatusCode, e.Description)
+	}
+	return fmt.Sprintf("OAuth error %q (%d)", e.Code, e.StatusCode)
+}
+
+// Token is an OAuth 2.0 token.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the access token can be used (it does not expire in the next seconds).
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+// PKCE holds a Proof Key for Code Exchange (RFC 7636), to create for each authorization.
+type PKCE struct {
+	Verifier  string
+	Challenge string // the S256 challenge of Verifier
+}
+
+// NewPKCE generates a random verifier, and its challenge.
+func NewPKCE() (*PKCE, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	return &PKCE{Verifier: verifier, Challenge: base64.RawURLEncoding.EncodeToString(sum[:])}, nil
+}
+
+// OAuthConfig describes an OAuth application.
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	AuthURL      string       // defaults to OAuthAuthURL
+	TokenURL     string       // defaults to OAuthTokenURL
+	HTTPClient   *http.Client // defaults to http.DefaultClient
+}
+
+// AuthCodeURL returns the URL of the consent page. The state is returned as is to
+// RedirectURL, and must be checked there to prevent CSRF.
+func (c *OAuthConfig) AuthCodeURL(state string, pkce *PKCE) string {
+	authURL := c.AuthURL
+	if authURL == "" {
+		authURL = OAuthAuthURL
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.ClientID)
+	query.Set("redirect_uri", c.RedirectURL)
+	query.Set("state", state)
+	if len(c.Scopes) > 0 {
+		query.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if pkce != nil {
+		query.Set("code_challenge", pkce.Challenge)
+		query.Set("code_challenge_method", "S256")
+	}
+	sep := "?"
+	if strings.Contains(authURL, "?") {
+		sep = "&"
+	}
+	return authURL + sep + query.Encode()
+}
+
+// Exchange trades the authorization code received on RedirectURL for a token.
+func (c *OAuthConfig) Exchange(ctx context.Context, code string, pkce *PKCE) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.RedirectURL)
+	if pkce != nil {
+		form.Set("code_verifier", pkce.Verifier)
+	}
+	return c.requestToken(ctx, form)
+}
+
+// Refresh gets a new token with a refresh token.
+func (c *OAuthConfig) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	t, err := c.requestToken(ctx, form)
+	if err == nil && t.RefreshToken == "" {
+		t.RefreshToken = refreshToken // ⬅︎ the refresh token can be kept by the server
+	}
+	return t, err
+}
+
+func (c *OAuthConfig) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = OAuthTokenURL
+	}
+	h := c.HTTPClient
+	if h == nil {
+		h = http.DefaultClient
+	}
+	form.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := h.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OAuth token endpoint could not be reached: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		oe := &OAuthError{StatusCode: res.StatusCode}
+		if err = json.NewDecoder(res.Body).Decode(oe); err != nil || oe.Code == "" {
+			oe.Code = "unknown_error"
+		}
+		return nil, oe
+	}
+	var response struct {
+		Token
+		ExpiresIn int64 `json:"expires_in"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("Could not decode the OAuth token: %w", err)
+	}
+	if response.AccessToken == "" {
+		return nil, &OAuthError{StatusCode: res.StatusCode, Code: "invalid_response", Description: "missing access_token"}
+	}
+	t := response.Token
+	if response.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return &t, nil
+}
+
+// TokenStore persists the token of a user. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Token returns the stored token, or nil.
+	Token(ctx context.Context) (*Token, error)
+	SaveToken(ctx context.Context, t *Token) error
+}
+
+// MemoryTokenStore keeps the token in memory.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewMemoryTokenStore returns a store holding t (which can be nil).
+func NewMemoryTokenStore(t *Token) *MemoryTokenStore {
+	return &MemoryTokenStore{token: t}
+}
+
+// Token returns the token.
+func (s *MemoryTokenStore) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, nil
+}
+
+// SaveToken replaces the token.
+func (s *MemoryTokenStore) SaveToken(ctx context.Context, t *Token) error {
+	s.mu.Lock()
+	s.token = t
+	s.mu.Unlock()
+	return nil
+}
+
+// FileTokenStore keeps the token in a JSON file, readable by the owner only.
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileTokenStore returns a store writing to path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Token reads the file, and returns nil when it does not exist.
+func (s *FileTokenStore) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t Token
+	if err = json.Unmarshal(buf, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveToken writes the file atomically.
+func (s *FileTokenStore) SaveToken(ctx context.Context, t *Token) error {
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// OAuthSource hands out valid access tokens, refreshing them when needed.
+type OAuthSource struct {
+	Config *OAuthConfig
+	Store  TokenStore
+
+	mu sync.Mutex
+}
+
+// NewOAuthSource returns a source using the tokens of store.
+func NewOAuthSource(config *OAuthConfig, store TokenStore) *OAuthSource {
+	return &OAuthSource{Config: config, Store: store}
+}
+
+// Token returns a valid token, refreshing (and saving) the stored token when it expired.
+func (s *OAuthSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.Store.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.Valid() {
+		return t, nil
+	}
+	return s.refresh(ctx, t)
+}
+
+// Refresh renews the token after the API rejected the access token stale, unless it was
+// renewed in the meantime.
+func (s *OAuthSource) Refresh(ctx context.Context, stale *Token) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.Store.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && stale != nil && t.AccessToken != stale.AccessToken && t.Valid() {
+		return t, nil
+	}
+	return s.refresh(ctx, t)
+}
+
+// refresh must be called with s.mu locked.
+func (s *OAuthSource) refresh(ctx context.Context, t *Token) (*Token, error) {
+	if t == nil || t.RefreshToken == "" {
+		return nil, ErrNoToken
+	}
+	fresh, err := s.Config.Refresh(ctx, t.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.Store.SaveToken(ctx, fresh); err != nil {
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// NewOAuthClient creates a Client acting on behalf of the user who authorized the application,
+// for the organization identified by slug. The access token is refreshed when it expires, or
+// when the API rejects it.
+func NewOAuthClient(slug string, source *OAuthSource, httpClient *http.Client) *Client {
+	c := NewClientWithCredentials(slug, nil, httpClient)
+	c.oauth = source
+	return c
+}
@@ -0,0 +1,146 @@
+package qonto_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// oauthServer is a stand-in for the token endpoint and the API.
+type oauthServer struct {
+	mu        sync.Mutex
+	challenge string
+	valid     string // the access token accepted by the API
+	refreshes int
+}
+
+func (s *oauthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path != "/token" {
+		if r.Header.Get("Authorization") != "Bearer "+s.valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"invalid token"}`)
+			return
+		}
+		fmt.Fprint(w, `{"organization":{"slug":"acme"}}`)
+		return
+	}
+
+	_ = r.ParseForm()
+	if r.Form.Get("client_id") != "app" || r.Form.Get("client_secret") != "app-secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid_client"}`)
+		return
+	}
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "the-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"bad code or verifier"}`)
+			return
+		}
+		s.valid = "access-1"
+		fmt.Fprint(w, `{"access_token":"access-1","refresh_token":"refresh-1","token_type":"bearer","expires_in":3600}`)
+	case "refresh_token":
+		if r.Form.Get("refresh_token") != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		s.refreshes++
+		s.valid = fmt.Sprintf("access-%d", s.refreshes+1)
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"bearer","expires_in":3600}`, s.valid)
+	}
+}
+
+func TestOAuth(t *testing.T) {
+	s := &oauthServer{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	config := &qonto.OAuthConfig{
+		ClientID:     "app",
+		ClientSecret: "app-secret",
+		RedirectURL:  "https://example.com/callback",
+		Scopes:       []string{qonto.ScopeOrganizationRead, qonto.ScopeOfflineAccess},
+		AuthURL:      srv.URL + "/auth",
+		TokenURL:     srv.URL + "/token",
+	}
+	pkce, err := qonto.NewPKCE()
+	if err != nil {
+		t.Fatalf("qonto.NewPKCE() failed: %v", err)
+	}
+	u, _ := url.Parse(config.AuthCodeURL("xyz", pkce))
+	q := u.Query()
+	if q.Get("state") != "xyz" || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "organization.read offline_access" {
+		t.Errorf("config.AuthCodeURL() == %s", u)
+	}
+	s.challenge = q.Get("code_challenge")
+
+	// the user consents, and we receive the code
+	ctx := context.Background()
+	if _, err = config.Exchange(ctx, "the-code", &qonto.PKCE{Verifier: "wrong"}); err == nil {
+		t.Fatalf("config.Exchange() should fail with a wrong verifier")
+	} else if oe := (*qonto.OAuthError)(nil); !errors.As(err, &oe) || oe.Code != "invalid_grant" {
+		t.Errorf("config.Exchange() == %v; want an invalid_grant error", err)
+	}
+	token, err := config.Exchange(ctx, "the-code", pkce)
+	if err != nil {
+		t.Fatalf("config.Exchange() failed: %v", err)
+	}
+	if !token.Valid() || token.RefreshToken != "refresh-1" {
+		t.Errorf("token == %+v", token)
+	}
+
+	store := qonto.NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+	if err = store.SaveToken(ctx, token); err != nil {
+		t.Fatalf("store.SaveToken() failed: %v", err)
+	}
+	c := qonto.NewOAuthClient("acme", qonto.NewOAuthSource(config, store), nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	if _, err = c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed: %v", err)
+	}
+
+	// the token is revoked: the client refreshes it, and tries again
+	s.mu.Lock()
+	s.valid = "access-2"
+	s.refreshes = 1
+	s.mu.Unlock()
+	if _, err = c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed after the revocation: %v", err)
+	}
+	// expired tokens are refreshed before the request
+	expired, _ := store.Token(ctx)
+	if expired.AccessToken != "access-3" || expired.RefreshToken != "refresh-1" {
+		t.Errorf("stored token == %+v; want the refreshed token", expired)
+	}
+	expired.Expiry = time.Now().Add(-time.Minute)
+	_ = store.SaveToken(ctx, expired)
+	if _, err = c.GetOrganization(); err != nil {
+		t.Fatalf("c.GetOrganization() failed after the expiration: %v", err)
+	}
+	if s.refreshes != 3 {
+		t.Errorf("refreshes == %d; want %d", s.refreshes, 3)
+	}
+}
+
+func TestOAuth_NoToken(t *testing.T) {
+	c := qonto.NewOAuthClient("acme", qonto.NewOAuthSource(&qonto.OAuthConfig{}, qonto.NewMemoryTokenStore(nil)), nil)
+	if _, err := c.GetOrganization(); !errors.Is(err, qonto.ErrNoToken) || !strings.Contains(err.Error(), "OAuth") {
+		t.Errorf("c.GetOrganization() == %v; want ErrNoToken", err)
+	}
+}
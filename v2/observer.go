@@ -0,0 +1,103 @@
+package qonto
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EndpointAttachmentFile is the endpoint of the downloads of attachment files, from their pre-signed URLs.
+const EndpointAttachmentFile = "attachment_file"
+
+// RequestInfo describes a request sent to the API.
+type RequestInfo struct {
+	Method string
+	// Endpoint is the path template, such as "/transactions/{id}/labels"
+	Endpoint     string
+	Organization string
+}
+
+// RequestResult describes the outcome of a request.
+type RequestResult struct {
+	StatusCode int // 0 when the API could not be reached
+	Duration   time.Duration
+	Bytes      int64 // the size of the response body read
+	Retries    int
+	CacheHit   bool // served from the cache, without request
+	// Revalidated is set when the API confirmed that the cached response is still fresh (304 Not Modified)
+	Revalidated bool
+	Err         error
+}
+
+// Observer receives the start and end of the requests, for metrics and tracing.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	// RequestStarted is called before the request is sent. The returned context is attached
+	// to the request, and passed to RequestFinished.
+	RequestStarted(ctx context.Context, info *RequestInfo) context.Context
+	RequestFinished(ctx context.Context, info *RequestInfo, result *RequestResult)
+}
+
+// Observers combines several observers in one.
+func Observers(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) RequestStarted(ctx context.Context, info *RequestInfo) context.Context {
+	for _, o := range m {
+		ctx = o.RequestStarted(ctx, info)
+	}
+	return ctx
+}
+
+func (m multiObserver) RequestFinished(ctx context.Context, info *RequestInfo, result *RequestResult) {
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i].RequestFinished(ctx, info, result)
+	}
+}
+
+// collections lists the API paths followed by an identifier.
+var collections = map[string]bool{
+	"organizations": true,
+	"transactions":  true,
+	"attachments":   true,
+	"labels":        true,
+	"memberships":   true,
+}
+
+// endpoint returns the path template of u, relative to the base URL of c.
+func (c *Client) endpoint(u *url.URL) string {
+	path := u.Path
+	if base, err := url.Parse(c.baseURL()); err == nil && u.Host == base.Host {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	return EndpointTemplate(path)
+}
+
+// EndpointTemplate replaces the identifiers of an API path by "{id}", for eg.
+// "/transactions/abc/labels" becomes "/transactions/{id}/labels".
+func EndpointTemplate(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		if collections[segments[i-1]] && !collections[segments[i]] {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// countingBody counts the bytes read from a response body.
+type countingBody struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	*b.n += int64(n)
+	return n, err
+}
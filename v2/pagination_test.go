@@ -0,0 +1,41 @@
+package qonto_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// redirectTransport sends all the requests to a test server.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestGetLabels_Pagination(t *testing.T) {
+	t.Parallel()
+	var queries []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		fmt.Fprint(w, `{"labels":[],"meta":{"current_page":3,"total_pages":3}}`)
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	c := qonto.NewClient("slug", "secret", &http.Client{Transport: redirectTransport{target}})
+	if _, err := c.GetLabels(3, 25); err != nil {
+		t.Fatalf("c.GetLabels() failed: %v", err)
+	}
+	if len(queries) != 1 || queries[0].Get("current_page") != "3" || queries[0].Get("per_page") != "25" {
+		t.Errorf("queries == %v; want current_page=3 and per_page=25", queries)
+	}
+}
@@ -0,0 +1,66 @@
+package qonto
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out the requests of one or more clients (token bucket).
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows requestsPerSecond on average, and bursts of up to burst requests (at
+// least 1). It panics if requestsPerSecond is not positive.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if !(requestsPerSecond > 0) { // ⬅︎ also rejects NaN
+		panic("qonto: non-positive rate for NewRateLimiter")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens-- // ⬅︎ we reserve a token, possibly in the future
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give back the reserved token
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
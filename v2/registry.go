@@ -0,0 +1,310 @@
+package qonto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Default values for the Registry.
+const (
+	DefaultRegistryConcurrency = 4
+	DefaultRequestsPerSecond   = 10
+	DefaultRequestsBurst       = 10
+)
+
+// ErrUnknownOrganization is returned by Registry.Client for unregistered names.
+var ErrUnknownOrganization = errors.New("Unknown organization")
+
+// ErrIncompleteCredentials is returned when the slug or secret key of an organization is missing.
+var ErrIncompleteCredentials = errors.New("Incomplete credentials")
+
+// Credentials holds the API credentials of a named organization.
+type Credentials struct {
+	Name      string `json:"name" yaml:"name"`
+	Slug      string `json:"slug" yaml:"slug"`
+	SecretKey string `json:"secret_key" yaml:"secret_key"`
+	// Provider supplies the secret key when SecretKey is empty
+	Provider CredentialsProvider `json:"-" yaml:"-"`
+}
+
+// Registry hands out the clients of several organizations.
+//
+// The clients share the same http.Client, RateLimiter and Cache.
+type Registry struct {
+	HTTPClient *http.Client
+	Limiter    *RateLimiter // NewRegistry sets it to DefaultRequestsPerSecond and DefaultRequestsBurst
+	Cache      Cache        // optional
+	// CacheTTLs overrides the lifetime of the cached responses by endpoint (optional, copied
+	// to each client, see Client.CacheTTLs)
+	CacheTTLs map[string]time.Duration
+	// Environment is the API environment of the clients (defaults to Production)
+	Environment Environment
+	// Middlewares wrap the HTTP requests of the clients
+	Middlewares []Middleware
+	// Concurrency is the maximum number of organizations called at once by ForEach
+	// (NewRegistry sets it to DefaultRegistryConcurrency)
+	Concurrency int
+
+	mu          sync.Mutex
+	credentials map[string]Credentials
+	clients     map[string]*Client
+}
+
+// NewRegistry creates an empty Registry, using httpClient (defaults to http.DefaultClient).
+func NewRegistry(httpClient *http.Client) *Registry {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Registry{
+		HTTPClient:  httpClient,
+		Limiter:     NewRateLimiter(DefaultRequestsPerSecond, DefaultRequestsBurst),
+		Concurrency: DefaultRegistryConcurrency,
+		credentials: make(map[string]Credentials),
+		clients:     make(map[string]*Client),
+	}
+}
+
+// Add registers (or replaces) the credentials of an organization.
+func (r *Registry) Add(c Credentials) error {
+	if c.Name == "" || c.Slug == "" || (c.SecretKey == "" && c.Provider == nil) {
+		return fmt.Errorf("%w for organization %q", ErrIncompleteCredentials, c.Name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.credentials[c.Name] = c
+	delete(r.clients, c.Name)
+	return nil
+}
+
+// LoadEnv registers the organizations found in the environment, as pairs of
+// "<prefix><NAME>_SLUG" and "<prefix><NAME>_SECRET_KEY" variables. Names are lowercased, so
+// QONTO_ACME_SLUG and QONTO_ACME_SECRET_KEY register "acme" when prefix is "QONTO_".
+func (r *Registry) LoadEnv(prefix string) error {
+	found := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		key := strings.SplitN(kv, "=", 2)[0]
+		if strings.HasPrefix(key, prefix) && strings.HasSuffix(key, "_SLUG") {
+			name := strings.TrimSuffix(strings.TrimPrefix(key, prefix), "_SLUG")
+			if name != "" {
+				found[name] = true
+			}
+		}
+	}
+	for _, name := range sortedKeys(found) {
+		err := r.Add(Credentials{
+			Name:      strings.ToLower(name),
+			Slug:      os.Getenv(prefix + name + "_SLUG"),
+			SecretKey: os.Getenv(prefix + name + "_SECRET_KEY"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile registers the organizations listed in a JSON or YAML file:
+//
+//	# organizations.yaml
+//	- name: acme
+//	  slug: acme-1234
+//	  secret_key: xxx
+func (r *Registry) LoadFile(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var list []Credentials
+	if err = yaml.UnmarshalStrict(buf, &list); err != nil {
+		return fmt.Errorf("Could not read credentials from %s: %w", path, err)
+	}
+	for _, c := range list {
+		if err = r.Add(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDir registers the organizations of a secrets directory, as mounted by Docker or Kubernetes:
+// each sub-directory is named after an organization, and holds the "slug" and "secret_key" files.
+// The secret keys are read with FileCredentials, so they can rotate.
+func (r *Registry) LoadDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		slug, err := ioutil.ReadFile(filepath.Join(dir, e.Name(), "slug"))
+		if err != nil {
+			return err
+		}
+		secretKey := filepath.Join(dir, e.Name(), "secret_key")
+		if _, err = os.Stat(secretKey); err != nil {
+			return err
+		}
+		err = r.Add(Credentials{
+			Name:     e.Name(),
+			Slug:     strings.TrimSpace(string(slug)),
+			Provider: NewFileCredentials(secretKey),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names returns the registered organizations, sorted.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.credentials))
+	for name := range r.credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client returns the client of an organization. Clients are created once, and reused.
+func (r *Registry) Client(name string) (*Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.clients[name]; ok {
+		return c, nil
+	}
+	creds, ok := r.credentials[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownOrganization, name)
+	}
+	provider := creds.Provider
+	if provider == nil {
+		provider = StaticCredentials(creds.SecretKey)
+	}
+	c := NewClientWithCredentials(creds.Slug, provider, r.HTTPClient)
+	c.Limiter = r.Limiter
+	c.Cache = r.Cache
+	if r.CacheTTLs != nil {
+		c.CacheTTLs = make(map[string]time.Duration, len(r.CacheTTLs))
+		for endpoint, ttl := range r.CacheTTLs {
+			c.CacheTTLs[endpoint] = ttl
+		}
+	}
+	c.Environment = r.Environment
+	c.Middlewares = r.Middlewares
+	r.clients[name] = c
+	return c, nil
+}
+
+// OrganizationResult is the result of a call made by Registry.ForEach.
+type OrganizationResult struct {
+	Name  string
+	Value interface{}
+	Err   error
+}
+
+// Results lists the results of Registry.ForEach, sorted by organization name.
+type Results []OrganizationResult
+
+// Err returns a *FanOutError when some organizations failed, or nil.
+func (rs Results) Err() error {
+	errs := make(map[string]error)
+	for _, r := range rs {
+		if r.Err != nil {
+			errs[r.Name] = r.Err
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &FanOutError{Errors: errs}
+}
+
+// FanOutError holds the errors of the failing organizations.
+type FanOutError struct {
+	Errors map[string]error
+}
+
+func (e *FanOutError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+	return fmt.Sprintf("%d organization(s) failed: %s", len(names), strings.Join(msgs, "; "))
+}
+
+// ForEach calls fn for every organization, concurrently, and collects the results.
+// A failing organization does not stop the others. Once ctx is cancelled, the organizations
+// still waiting for their turn get the error of ctx.
+func (r *Registry) ForEach(ctx context.Context, fn func(ctx context.Context, name string, c *Client) (interface{}, error)) Results {
+	names := r.Names()
+	results := make(Results, len(names))
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultRegistryConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i].Name = name
+		c, err := r.Client(name)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err = acquire(ctx, sem); err != nil {
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(res *OrganizationResult, c *Client) {
+			defer func() { <-sem; wg.Done() }()
+			res.Value, res.Err = fn(ctx, res.Name, c)
+		}(&results[i], c)
+	}
+	wg.Wait()
+	return results
+}
+
+// acquire takes a slot of sem, unless ctx is cancelled first.
+func acquire(ctx context.Context, sem chan struct{}) error {
+	if err := ctx.Err(); err != nil {
+		return err // ⬅︎ select would pick randomly when both are ready
+	}
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package qonto_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestRegistry(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch auth := r.Header.Get("Authorization"); auth {
+		case "acme-1:secret-acme", "globex-2:secret-globex":
+			fmt.Fprintf(w, `{"organization":{"slug":%q}}`, auth[:6])
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"bad credentials"}`)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "organizations.yaml")
+	yaml := "- name: acme\n  slug: acme-1\n  secret_key: secret-acme\n"
+	if err := ioutil.WriteFile(file, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secrets := filepath.Join(dir, "secrets", "initech")
+	if err := os.MkdirAll(secrets, 0700); err != nil {
+		t.Fatal(err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(secrets, "slug"), []byte("initech-3\n"), 0600)
+	_ = ioutil.WriteFile(filepath.Join(secrets, "secret_key"), []byte("wrong\n"), 0600)
+	os.Setenv("TEST_QONTO_GLOBEX_SLUG", "globex-2")
+	os.Setenv("TEST_QONTO_GLOBEX_SECRET_KEY", "secret-globex")
+	defer os.Unsetenv("TEST_QONTO_GLOBEX_SLUG")
+	defer os.Unsetenv("TEST_QONTO_GLOBEX_SECRET_KEY")
+
+	r := qonto.NewRegistry(nil)
+	r.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	r.Cache = qonto.NewMemoryCache()
+	if err := r.LoadFile(file); err != nil {
+		t.Fatalf("r.LoadFile() failed: %v", err)
+	}
+	if err := r.LoadDir(filepath.Join(dir, "secrets")); err != nil {
+		t.Fatalf("r.LoadDir() failed: %v", err)
+	}
+	if err := r.LoadEnv("TEST_QONTO_"); err != nil {
+		t.Fatalf("r.LoadEnv() failed: %v", err)
+	}
+	if names := fmt.Sprint(r.Names()); names != "[acme globex initech]" {
+		t.Errorf("r.Names() == %s; want [acme globex initech]", names)
+	}
+	if _, err := r.Client("unknown"); !errors.Is(err, qonto.ErrUnknownOrganization) {
+		t.Errorf("r.Client(unknown) == %v; want ErrUnknownOrganization", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		results := r.ForEach(context.Background(), func(ctx context.Context, name string, c *qonto.Client) (interface{}, error) {
+			return c.GetOrganizationContext(ctx)
+		})
+		if len(results) != 3 || results[0].Value.(*qonto.Organization).Slug != "acme-1" || results[1].Value.(*qonto.Organization).Slug != "globex" {
+			t.Fatalf("results == %+v", results)
+		}
+		var fe *qonto.FanOutError
+		if err := results.Err(); !errors.As(err, &fe) || len(fe.Errors) != 1 || fe.Errors["initech"] == nil {
+			t.Errorf("results.Err() == %v; want an error for initech", err)
+		}
+	}
+	// the successful responses are cached
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf("requests == %d; want %d", n, 4)
+	}
+}
+
+func TestRegistry_ForEachCancelled(t *testing.T) {
+	r := qonto.NewRegistry(nil)
+	r.Concurrency = 1
+	for _, name := range []string{"a", "b", "c"} {
+		if err := r.Add(qonto.Credentials{Name: name, Slug: name, SecretKey: "secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	results := r.ForEach(ctx, func(ctx context.Context, name string, c *qonto.Client) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn was called %d times; want 1", n)
+	}
+	for _, res := range results {
+		if res.Err != context.Canceled {
+			t.Errorf("results[%s].Err == %v; want %v", res.Name, res.Err, context.Canceled)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := qonto.NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("l.Wait() failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("4 requests took %v; want at least 20ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l = qonto.NewRateLimiter(1, 1)
+	_ = l.Wait(ctx)
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("l.Wait() == %v; want %v", err, context.Canceled)
+	}
+
+	// a burst under 1 allows a single request
+	l = qonto.NewRateLimiter(1, 0)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("l.Wait() == %v with no burst; want nil", err)
+	}
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("l.Wait() == %v with no burst; want %v", err, context.Canceled)
+	}
+}
+
+func TestNewRateLimiter_InvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("qonto.NewRateLimiter(%v, 1) did not panic", rate)
+				}
+			}()
+			qonto.NewRateLimiter(rate, 1)
+		}()
+	}
+}
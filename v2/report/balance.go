@@ -0,0 +1,104 @@
+package report
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// ErrInvalidPeriod is returned when the end of a period is before its start.
+var ErrInvalidPeriod = errors.New("The end of the period is before its start")
+
+// DailyBalance holds the balances of a bank account at the end of a day.
+type DailyBalance struct {
+	Date            time.Time // midnight (start) of the day, in the requested location
+	BookedCents     int64     // the balance, as it appears on bank statements
+	AuthorizedCents int64     // the balance minus the pending operations
+}
+
+// balanceEffect is the change brought by a transaction to one of the balances.
+type balanceEffect struct {
+	at    time.Time
+	cents int64
+}
+
+// BalanceHistory reconstructs the end-of-day balances of ba for every day between from and to
+// (both included), starting from the current balances and walking the transactions backwards.
+//
+// The transactions must include all the transactions emitted or settled since from, in any
+// order. Completed transactions change the booked balance when settled, and the authorized
+// balance when emitted; pending transactions only change the authorized balance. Declined and
+// reversed transactions are ignored.
+//
+// Days are computed in loc (defaults to UTC).
+func BalanceHistory(ba *qonto.BankAccount, transactions []*qonto.Transaction, from, to time.Time, loc *time.Location) ([]DailyBalance, error) {
+	if ba == nil {
+		return nil, qonto.ErrBankAccountNeeded
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	first := startOfDay(from, loc)
+	last := startOfDay(to, loc)
+	if last.Before(first) {
+		return nil, ErrInvalidPeriod
+	}
+
+	var booked, authorized []balanceEffect
+	for _, t := range transactions {
+		cents := SignedCents(t)
+		switch t.Status {
+		case qonto.TransactionStatusCompleted:
+			settledAt := t.EmittedAt
+			if t.SettledAt != nil {
+				settledAt = *t.SettledAt
+			}
+			booked = append(booked, balanceEffect{at: settledAt, cents: cents})
+			authorized = append(authorized, balanceEffect{at: t.EmittedAt, cents: cents})
+		case qonto.TransactionStatusPending:
+			authorized = append(authorized, balanceEffect{at: t.EmittedAt, cents: cents})
+		}
+	}
+	sortEffects(booked)
+	sortEffects(authorized)
+
+	// we walk the days backwards, removing the effects that happened after the end of each day
+	var days []time.Time
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	res := make([]DailyBalance, len(days))
+	bookedCents, authorizedCents := ba.BalanceCents, ba.AuthorizedBalanceCents
+	bi, ai := 0, 0
+	for i := len(days) - 1; i >= 0; i-- {
+		end := days[i].AddDate(0, 0, 1)
+		for ; bi < len(booked) && !booked[bi].at.Before(end); bi++ {
+			bookedCents -= booked[bi].cents
+		}
+		for ; ai < len(authorized) && !authorized[ai].at.Before(end); ai++ {
+			authorizedCents -= authorized[ai].cents
+		}
+		res[i] = DailyBalance{Date: days[i], BookedCents: bookedCents, AuthorizedCents: authorizedCents}
+	}
+	return res, nil
+}
+
+// SignedCents returns the amount of t in cents, negative for debits.
+func SignedCents(t *qonto.Transaction) int64 {
+	if t.Side == qonto.TransactionSideDebit {
+		return -t.AmountCents
+	}
+	return t.AmountCents
+}
+
+// sortEffects sorts the effects, most recent first.
+func sortEffects(effects []balanceEffect) {
+	sort.Slice(effects, func(i, j int) bool { return effects[i].at.After(effects[j].at) })
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,53 @@
+package report_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+func day(d, h int) time.Time {
+	return time.Date(2020, 1, d, h, 0, 0, 0, time.UTC)
+}
+
+func TestBalanceHistory(t *testing.T) {
+	settled := func(d int) *time.Time { s := day(d, 12); return &s }
+	ba := &qonto.BankAccount{BalanceCents: 100000, AuthorizedBalanceCents: 95000}
+	transactions := []*qonto.Transaction{
+		// a credit on day 2
+		{Side: qonto.TransactionSideCredit, AmountCents: 20000, Status: qonto.TransactionStatusCompleted, EmittedAt: day(2, 10), SettledAt: settled(2)},
+		// a card payment emitted on day 2, settled on day 3
+		{Side: qonto.TransactionSideDebit, AmountCents: 3000, Status: qonto.TransactionStatusCompleted, EmittedAt: day(2, 18), SettledAt: settled(3)},
+		// a pending payment on day 3
+		{Side: qonto.TransactionSideDebit, AmountCents: 5000, Status: qonto.TransactionStatusPending, EmittedAt: day(3, 9)},
+		// a declined payment is ignored
+		{Side: qonto.TransactionSideDebit, AmountCents: 99999, Status: qonto.TransactionStatusDeclined, EmittedAt: day(2, 9)},
+	}
+
+	history, err := report.BalanceHistory(ba, transactions, day(1, 8), day(3, 8), nil)
+	if err != nil {
+		t.Fatalf("report.BalanceHistory() failed: %v", err)
+	}
+	want := []report.DailyBalance{
+		{Date: day(1, 0), BookedCents: 83000, AuthorizedCents: 83000},
+		{Date: day(2, 0), BookedCents: 103000, AuthorizedCents: 100000},
+		{Date: day(3, 0), BookedCents: 100000, AuthorizedCents: 95000},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("len(history) == %d; want %d", len(history), len(want))
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("history[%d] == %+v; want %+v", i, history[i], want[i])
+		}
+	}
+}
+
+func TestBalanceHistory_InvalidPeriod(t *testing.T) {
+	_, err := report.BalanceHistory(&qonto.BankAccount{}, nil, day(2, 0), day(1, 0), nil)
+	if err != report.ErrInvalidPeriod {
+		t.Errorf("err == %v; want %v", err, report.ErrInvalidPeriod)
+	}
+}
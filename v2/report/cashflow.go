@@ -0,0 +1,329 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// Dimension is the key used to group transactions in a CashFlow report.
+type Dimension string
+
+const (
+	// ByMonth groups the transactions by month ("2006-01").
+	ByMonth Dimension = "month"
+	// ByLabel groups the transactions by label path ("Ops/Travel"); parent labels include their children.
+	ByLabel Dimension = "label"
+	// ByOperationType groups the transactions by operation type ("card", "transfer" etc.).
+	ByOperationType Dimension = "operation_type"
+	// BySide groups the transactions by side ("debit" or "credit").
+	BySide Dimension = "side"
+	// ByMember groups the transactions by initiator.
+	ByMember Dimension = "member"
+)
+
+// NoValue is the key of the line holding the transactions without label (or member).
+const NoValue = "(none)"
+
+// Totals holds the aggregated amounts of a group of transactions.
+type Totals struct {
+	InflowCents  int64 `json:"inflow_cents"`
+	OutflowCents int64 `json:"outflow_cents"` // positive amount
+	NetCents     int64 `json:"net_cents"`
+	Count        int   `json:"count"`
+}
+
+func (t *Totals) add(tr *qonto.Transaction) {
+	if tr.Side == qonto.TransactionSideDebit {
+		t.OutflowCents += tr.AmountCents
+	} else {
+		t.InflowCents += tr.AmountCents
+	}
+	t.NetCents = t.InflowCents - t.OutflowCents
+	t.Count++
+}
+
+// Line holds the totals for a single key of a CashFlow report.
+type Line struct {
+	Key      string  `json:"key"`
+	Totals   Totals  `json:"totals"`
+	Previous *Totals `json:"previous,omitempty"` // the totals for the previous period (when compared)
+}
+
+// CashFlow is a report of the inflows and outflows over a period.
+type CashFlow struct {
+	GroupBy       Dimension `json:"group_by"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Lines         []Line    `json:"lines"`
+	Total         Totals    `json:"total"`
+	PreviousTotal *Totals   `json:"previous_total,omitempty"`
+}
+
+// CashFlowOptions configures CashFlowReport.
+type CashFlowOptions struct {
+	GroupBy Dimension // defaults to ByMonth
+	From    time.Time // start of the period (inclusive)
+	To      time.Time // end of the period (exclusive)
+	// Compare adds the totals of the previous period, of the same duration. When grouped ByMonth,
+	// each month is compared with the month as many months before as the period spans
+	Compare bool
+	// Statuses lists the statuses of the transactions to include (defaults to completed only)
+	Statuses []qonto.TransactionStatus
+	// Labels is needed to group transactions ByLabel
+	Labels []qonto.Label
+	// Memberships is used to display member names when grouping ByMember
+	Memberships []qonto.Membership
+	// Location is used to compute months (defaults to UTC)
+	Location *time.Location
+}
+
+// CashFlowReport aggregates the transactions settled between opt.From and opt.To.
+// Transactions without settlement date are counted on their emission date.
+func CashFlowReport(transactions []*qonto.Transaction, opt CashFlowOptions) (*CashFlow, error) {
+	if opt.To.Before(opt.From) {
+		return nil, ErrInvalidPeriod
+	}
+	if opt.GroupBy == "" {
+		opt.GroupBy = ByMonth
+	}
+	if len(opt.Statuses) == 0 {
+		opt.Statuses = []qonto.TransactionStatus{qonto.TransactionStatusCompleted}
+	}
+	if opt.Location == nil {
+		opt.Location = time.UTC
+	}
+	keys, err := keyFunc(&opt)
+	if err != nil {
+		return nil, err
+	}
+	previousFrom := opt.From.Add(-opt.To.Sub(opt.From))
+	previousKeys := keys
+	if opt.GroupBy == ByMonth {
+		// the previous months are moved forward to the months they are compared with
+		shift := monthsBetween(previousFrom.In(opt.Location), opt.From.In(opt.Location))
+		previousKeys = func(t *qonto.Transaction) []string {
+			at := bookingDate(t).In(opt.Location)
+			return []string{time.Date(at.Year(), at.Month()+time.Month(shift), 1, 0, 0, 0, 0, opt.Location).Format("2006-01")}
+		}
+	}
+
+	r := &CashFlow{GroupBy: opt.GroupBy, From: opt.From, To: opt.To}
+	current := make(map[string]*Totals)
+	previous := make(map[string]*Totals)
+	if opt.Compare {
+		r.PreviousTotal = &Totals{}
+	}
+
+	for _, t := range transactions {
+		if !hasStatus(t, opt.Statuses) {
+			continue
+		}
+		at := bookingDate(t)
+		totals, total, keysOf := current, &r.Total, keys
+		switch {
+		case !at.Before(opt.From) && at.Before(opt.To):
+		case opt.Compare && !at.Before(previousFrom) && at.Before(opt.From):
+			totals, total, keysOf = previous, r.PreviousTotal, previousKeys
+		default:
+			continue
+		}
+		total.add(t)
+		for _, k := range keysOf(t) {
+			if totals[k] == nil {
+				totals[k] = &Totals{}
+			}
+			totals[k].add(t)
+		}
+	}
+
+	for k, t := range current {
+		r.Lines = append(r.Lines, Line{Key: k, Totals: *t, Previous: previous[k]})
+	}
+	if opt.Compare {
+		// keys only present in the previous period are listed too
+		for k, t := range previous {
+			if current[k] == nil {
+				r.Lines = append(r.Lines, Line{Key: k, Previous: t})
+			}
+		}
+		for i := range r.Lines {
+			if r.Lines[i].Previous == nil {
+				r.Lines[i].Previous = &Totals{}
+			}
+		}
+	}
+	sort.Slice(r.Lines, func(i, j int) bool { return r.Lines[i].Key < r.Lines[j].Key })
+	return r, nil
+}
+
+// keyFunc returns the function listing the keys of a transaction for opt.GroupBy.
+func keyFunc(opt *CashFlowOptions) (func(t *qonto.Transaction) []string, error) {
+	switch opt.GroupBy {
+	case ByMonth:
+		return func(t *qonto.Transaction) []string {
+			return []string{bookingDate(t).In(opt.Location).Format("2006-01")}
+		}, nil
+	case ByOperationType:
+		return func(t *qonto.Transaction) []string { return []string{string(t.OperationType)} }, nil
+	case BySide:
+		return func(t *qonto.Transaction) []string { return []string{string(t.Side)} }, nil
+	case ByMember:
+		names := make(map[string]string, len(opt.Memberships))
+		for _, m := range opt.Memberships {
+			names[m.ID] = strings.TrimSpace(m.FirstName + " " + m.LastName)
+		}
+		return func(t *qonto.Transaction) []string {
+			if t.InitiatorID == nil || *t.InitiatorID == "" {
+				return []string{NoValue}
+			}
+			if name, ok := names[*t.InitiatorID]; ok && name != "" {
+				return []string{name}
+			}
+			return []string{*t.InitiatorID}
+		}, nil
+	case ByLabel:
+		paths := labelPaths(opt.Labels)
+		return func(t *qonto.Transaction) []string {
+			// a transaction counts once in each of its labels and their ancestors
+			seen := make(map[string]bool)
+			var keys []string
+			for _, id := range t.LabelIDs {
+				for _, p := range paths[id] {
+					if !seen[p] {
+						seen[p] = true
+						keys = append(keys, p)
+					}
+				}
+			}
+			if len(keys) == 0 {
+				return []string{NoValue}
+			}
+			return keys
+		}, nil
+	default:
+		return nil, fmt.Errorf("Unknown report dimension %q", opt.GroupBy)
+	}
+}
+
+// labelPaths returns, for each label ID, the paths of the label and all its ancestors
+// ("Ops", "Ops/Travel").
+func labelPaths(labels []qonto.Label) map[string][]string {
+	tree := qonto.NewLabelTree(labels)
+	res := make(map[string][]string, len(labels))
+	for _, l := range labels {
+		ancestors := tree.Ancestors(l.ID)
+		paths := make([]string, 0, len(ancestors)+1)
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			paths = append(paths, tree.Path(ancestors[i].ID))
+		}
+		res[l.ID] = append(paths, tree.Path(l.ID))
+	}
+	return res
+}
+
+// monthsBetween returns the number of months from the month of a to the month of b.
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
+
+func bookingDate(t *qonto.Transaction) time.Time {
+	if t.SettledAt != nil {
+		return *t.SettledAt
+	}
+	return t.EmittedAt
+}
+
+func hasStatus(t *qonto.Transaction, statuses []qonto.TransactionStatus) bool {
+	for _, s := range statuses {
+		if s == t.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *CashFlow) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the report as CSV, amounts in cents.
+func (r *CashFlow) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{string(r.GroupBy), "inflow_cents", "outflow_cents", "net_cents", "count"}
+	if r.PreviousTotal != nil {
+		header = append(header, "previous_inflow_cents", "previous_outflow_cents", "previous_net_cents", "previous_count")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	write := func(key string, t Totals, p *Totals) error {
+		record := append([]string{key}, totalsRecord(t)...)
+		if r.PreviousTotal != nil {
+			record = append(record, totalsRecord(*p)...)
+		}
+		return cw.Write(record)
+	}
+	for _, l := range r.Lines {
+		if err := write(l.Key, l.Totals, l.Previous); err != nil {
+			return err
+		}
+	}
+	if err := write("total", r.Total, r.PreviousTotal); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func totalsRecord(t Totals) []string {
+	return []string{
+		strconv.FormatInt(t.InflowCents, 10),
+		strconv.FormatInt(t.OutflowCents, 10),
+		strconv.FormatInt(t.NetCents, 10),
+		strconv.Itoa(t.Count),
+	}
+}
+
+// WriteTable writes the report as a text table, for terminals.
+func (r *CashFlow) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := strings.ToUpper(string(r.GroupBy)) + "\tIN\tOUT\tNET\t"
+	if r.PreviousTotal != nil {
+		header += "PREV NET\tCHANGE\t"
+	}
+	fmt.Fprintln(tw, header)
+	write := func(key string, t Totals, p *Totals) {
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t", key, FormatCents(t.InflowCents), FormatCents(t.OutflowCents), FormatCents(t.NetCents))
+		if r.PreviousTotal != nil {
+			line += fmt.Sprintf("%s\t%s\t", FormatCents(p.NetCents), FormatCents(t.NetCents-p.NetCents))
+		}
+		fmt.Fprintln(tw, line)
+	}
+	for _, l := range r.Lines {
+		write(l.Key, l.Totals, l.Previous)
+	}
+	write("TOTAL", r.Total, r.PreviousTotal)
+	return tw.Flush()
+}
+
+// FormatCents formats an amount in cents as a decimal number ("-1234.56").
+func FormatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,135 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+func cashFlowTransactions() []*qonto.Transaction {
+	month := func(m time.Month) time.Time { return time.Date(2020, m, 10, 0, 0, 0, 0, time.UTC) }
+	return []*qonto.Transaction{
+		{Side: qonto.TransactionSideCredit, AmountCents: 100000, Status: qonto.TransactionStatusCompleted, EmittedAt: month(2), OperationType: qonto.OperationTypeTransfer},
+		{Side: qonto.TransactionSideDebit, AmountCents: 5000, Status: qonto.TransactionStatusCompleted, EmittedAt: month(2), OperationType: qonto.OperationTypeCard, LabelIDs: []string{"train"}},
+		{Side: qonto.TransactionSideDebit, AmountCents: 2000, Status: qonto.TransactionStatusCompleted, EmittedAt: month(2), OperationType: qonto.OperationTypeCard, LabelIDs: []string{"ops"}},
+		{Side: qonto.TransactionSideDebit, AmountCents: 999, Status: qonto.TransactionStatusPending, EmittedAt: month(2), OperationType: qonto.OperationTypeCard},
+		{Side: qonto.TransactionSideDebit, AmountCents: 3000, Status: qonto.TransactionStatusCompleted, EmittedAt: month(1), OperationType: qonto.OperationTypeCard, LabelIDs: []string{"train"}},
+	}
+}
+
+func TestCashFlowReport_ByLabel(t *testing.T) {
+	ops := "ops"
+	r, err := report.CashFlowReport(cashFlowTransactions(), report.CashFlowOptions{
+		GroupBy: report.ByLabel,
+		From:    time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		Compare: true,
+		Labels:  []qonto.Label{{ID: "ops", Name: "Ops"}, {ID: "train", Name: "Train", ParentID: &ops}},
+	})
+	if err != nil {
+		t.Fatalf("report.CashFlowReport() failed: %v", err)
+	}
+
+	want := map[string][2]int64{ // key ➡︎ {current net, previous net}
+		report.NoValue: {100000, 0},
+		"Ops":          {-7000, -3000},
+		"Ops/Train":    {-5000, -3000},
+	}
+	if len(r.Lines) != len(want) {
+		t.Fatalf("r.Lines == %+v; want %d lines", r.Lines, len(want))
+	}
+	for _, l := range r.Lines {
+		w, ok := want[l.Key]
+		if !ok {
+			t.Errorf("unexpected line %q", l.Key)
+			continue
+		}
+		if l.Totals.NetCents != w[0] || l.Previous.NetCents != w[1] {
+			t.Errorf("line %q: net == %d (previous %d); want %d (previous %d)", l.Key, l.Totals.NetCents, l.Previous.NetCents, w[0], w[1])
+		}
+	}
+	if r.Total.InflowCents != 100000 || r.Total.OutflowCents != 7000 || r.Total.Count != 3 {
+		t.Errorf("r.Total == %+v; want 1000.00 in, 70.00 out, 3 transactions", r.Total)
+	}
+	if r.PreviousTotal.NetCents != -3000 {
+		t.Errorf("r.PreviousTotal.NetCents == %d; want %d", r.PreviousTotal.NetCents, -3000)
+	}
+}
+
+func TestCashFlowReport_CompareByMonth(t *testing.T) {
+	transactions := append(cashFlowTransactions(), &qonto.Transaction{
+		Side: qonto.TransactionSideDebit, AmountCents: 1000, Status: qonto.TransactionStatusCompleted, EmittedAt: time.Date(2019, 12, 10, 0, 0, 0, 0, time.UTC),
+	})
+	r, err := report.CashFlowReport(transactions, report.CashFlowOptions{
+		GroupBy: report.ByMonth,
+		From:    time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+		Compare: true,
+	})
+	if err != nil {
+		t.Fatalf("report.CashFlowReport() failed: %v", err)
+	}
+
+	// February is compared with December, and March with January
+	want := map[string][2]int64{ // key ➡︎ {current net, previous net}
+		"2020-02": {93000, -1000},
+		"2020-03": {0, -3000},
+	}
+	if len(r.Lines) != len(want) {
+		t.Fatalf("r.Lines == %+v; want %d lines", r.Lines, len(want))
+	}
+	for _, l := range r.Lines {
+		w, ok := want[l.Key]
+		if !ok {
+			t.Errorf("unexpected line %q", l.Key)
+			continue
+		}
+		if l.Totals.NetCents != w[0] || l.Previous.NetCents != w[1] {
+			t.Errorf("line %q: net == %d (previous %d); want %d (previous %d)", l.Key, l.Totals.NetCents, l.Previous.NetCents, w[0], w[1])
+		}
+	}
+}
+
+func TestCashFlow_Write(t *testing.T) {
+	r, err := report.CashFlowReport(cashFlowTransactions(), report.CashFlowOptions{
+		GroupBy: report.ByOperationType,
+		From:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("report.CashFlowReport() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = r.WriteCSV(&buf); err != nil {
+		t.Fatalf("r.WriteCSV() failed: %v", err)
+	}
+	want := "operation_type,inflow_cents,outflow_cents,net_cents,count\n" +
+		"card,0,10000,-10000,3\n" +
+		"transfer,100000,0,100000,1\n" +
+		"total,100000,10000,90000,4\n"
+	if buf.String() != want {
+		t.Errorf("CSV == %q; want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err = r.WriteTable(&buf); err != nil {
+		t.Fatalf("r.WriteTable() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "900.00") {
+		t.Errorf("table should contain the net total:\n%s", buf.String())
+	}
+}
+
+func TestFormatCents(t *testing.T) {
+	tests := map[int64]string{0: "0.00", 5: "0.05", -12042: "-120.42", 100000: "1000.00"}
+	for cents, want := range tests {
+		if got := report.FormatCents(cents); got != want {
+			t.Errorf("report.FormatCents(%d) == %q; want %q", cents, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+/*
+Package report computes reports from Qonto transactions.
+
+All the functions work on slices of transactions (downloaded with GetAllTransactions, or
+read from a store.Store), and all the amounts are computed in cents to stay exact.
+
+Example:
+
+	ba, _ := c.GetBankAccount()
+	transactions, _ := c.GetAllTransactionsForAccount(ba, nil)
+
+	// end-of-day balances for the last 30 days
+	now := time.Now()
+	history, _ := report.BalanceHistory(ba, transactions, now.AddDate(0, 0, -30), now, nil)
+*/
+package report
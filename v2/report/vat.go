@@ -0,0 +1,221 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// VATIssue flags a transaction that needs to be checked before a VAT declaration.
+type VATIssue string
+
+const (
+	// VATIssueMissingVAT flags a transaction without VAT amount or rate.
+	VATIssueMissingVAT VATIssue = "missing_vat"
+	// VATIssueMissingAttachment flags a transaction requiring an attachment that has none (or lost it).
+	VATIssueMissingAttachment VATIssue = "missing_attachment"
+)
+
+// VATRate holds the VAT totals for a single rate.
+type VATRate struct {
+	Rate            *float64 `json:"rate"`             // nil for the transactions without VAT rate
+	DeductibleCents int64    `json:"deductible_cents"` // VAT paid on debits
+	CollectedCents  int64    `json:"collected_cents"`  // VAT received on credits
+	DebitBaseCents  int64    `json:"debit_base_cents"` // debits, VAT excluded
+	CreditBaseCents int64    `json:"credit_base_cents"`
+	Count           int      `json:"count"`
+}
+
+// Key returns the rate formatted for display ("20", "5.5"), or NoValue.
+func (r *VATRate) Key() string {
+	if r.Rate == nil {
+		return NoValue
+	}
+	return strconv.FormatFloat(*r.Rate, 'f', -1, 64)
+}
+
+// VATLine holds a single transaction of a VATSummary, with its issues (if any).
+type VATLine struct {
+	Transaction *qonto.Transaction `json:"transaction"`
+	Issues      []VATIssue         `json:"issues,omitempty"`
+}
+
+// VATSummary summarizes the VAT over a period.
+type VATSummary struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	Rates           []VATRate `json:"rates"`
+	DeductibleCents int64     `json:"deductible_cents"`
+	CollectedCents  int64     `json:"collected_cents"`
+	DueCents        int64     `json:"due_cents"` // collected - deductible (negative for a VAT credit)
+	Lines           []VATLine `json:"lines"`
+}
+
+// VATOptions configures VATReport.
+type VATOptions struct {
+	From time.Time // start of the period (inclusive)
+	To   time.Time // end of the period (exclusive)
+	// Statuses lists the statuses of the transactions to include (defaults to completed only)
+	Statuses []qonto.TransactionStatus
+}
+
+// VATReport groups the transactions settled between opt.From and opt.To by VAT rate, and
+// flags the transactions with missing VAT or missing attachments.
+func VATReport(transactions []*qonto.Transaction, opt VATOptions) (*VATSummary, error) {
+	if opt.To.Before(opt.From) {
+		return nil, ErrInvalidPeriod
+	}
+	if len(opt.Statuses) == 0 {
+		opt.Statuses = []qonto.TransactionStatus{qonto.TransactionStatusCompleted}
+	}
+
+	s := &VATSummary{From: opt.From, To: opt.To}
+	rates := make(map[string]*VATRate)
+	for _, t := range transactions {
+		at := bookingDate(t)
+		if !hasStatus(t, opt.Statuses) || at.Before(opt.From) || !at.Before(opt.To) {
+			continue
+		}
+
+		line := VATLine{Transaction: t}
+		if t.VATAmountCents == nil || t.VATRate == nil {
+			line.Issues = append(line.Issues, VATIssueMissingVAT)
+		}
+		if t.AttachmentLost || (t.AttachmentRequired && len(t.AttachmentIDs) == 0) {
+			line.Issues = append(line.Issues, VATIssueMissingAttachment)
+		}
+		s.Lines = append(s.Lines, line)
+
+		r := &VATRate{Rate: t.VATRate}
+		if rates[r.Key()] == nil {
+			rates[r.Key()] = r
+		}
+		r = rates[r.Key()]
+		var vat int64
+		if t.VATAmountCents != nil {
+			vat = *t.VATAmountCents
+		}
+		if t.Side == qonto.TransactionSideDebit {
+			r.DeductibleCents += vat
+			r.DebitBaseCents += t.AmountCents - vat
+			s.DeductibleCents += vat
+		} else {
+			r.CollectedCents += vat
+			r.CreditBaseCents += t.AmountCents - vat
+			s.CollectedCents += vat
+		}
+		r.Count++
+	}
+	s.DueCents = s.CollectedCents - s.DeductibleCents
+
+	for _, r := range rates {
+		s.Rates = append(s.Rates, *r)
+	}
+	// rates are sorted in increasing order, the missing rate last
+	sort.Slice(s.Rates, func(i, j int) bool {
+		ri, rj := s.Rates[i].Rate, s.Rates[j].Rate
+		if ri == nil || rj == nil {
+			return rj == nil && ri != nil
+		}
+		return *ri < *rj
+	})
+	sort.SliceStable(s.Lines, func(i, j int) bool {
+		return bookingDate(s.Lines[i].Transaction).Before(bookingDate(s.Lines[j].Transaction))
+	})
+	return s, nil
+}
+
+// Flagged returns the lines with at least one issue.
+func (s *VATSummary) Flagged() []VATLine {
+	var res []VATLine
+	for _, l := range s.Lines {
+		if len(l.Issues) > 0 {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// WriteJSON writes the summary and all the lines as indented JSON.
+func (s *VATSummary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteCSV writes the totals by VAT rate as CSV, amounts in cents.
+func (s *VATSummary) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{"vat_rate", "deductible_cents", "collected_cents", "debit_base_cents", "credit_base_cents", "count"}}
+	for _, r := range s.Rates {
+		records = append(records, []string{
+			r.Key(),
+			strconv.FormatInt(r.DeductibleCents, 10),
+			strconv.FormatInt(r.CollectedCents, 10),
+			strconv.FormatInt(r.DebitBaseCents, 10),
+			strconv.FormatInt(r.CreditBaseCents, 10),
+			strconv.Itoa(r.Count),
+		})
+	}
+	return cw.WriteAll(records)
+}
+
+// WriteLinesCSV writes the detail of the transactions as CSV, amounts in cents.
+func (s *VATSummary) WriteLinesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{"transaction_id", "date", "side", "label", "amount_cents", "vat_rate", "vat_amount_cents", "issues"}}
+	for _, l := range s.Lines {
+		t := l.Transaction
+		r := VATRate{Rate: t.VATRate}
+		vat := ""
+		if t.VATAmountCents != nil {
+			vat = strconv.FormatInt(*t.VATAmountCents, 10)
+		}
+		label := ""
+		if t.Label != nil {
+			label = *t.Label
+		}
+		issues := make([]string, len(l.Issues))
+		for i, issue := range l.Issues {
+			issues[i] = string(issue)
+		}
+		records = append(records, []string{
+			t.ID,
+			bookingDate(t).Format(time.RFC3339),
+			string(t.Side),
+			label,
+			strconv.FormatInt(t.AmountCents, 10),
+			r.Key(),
+			vat,
+			strings.Join(issues, "|"),
+		})
+	}
+	return cw.WriteAll(records)
+}
+
+// WriteTable writes the totals by VAT rate as a text table, for terminals.
+func (s *VATSummary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "RATE\tDEDUCTIBLE\tCOLLECTED\tCOUNT\t")
+	for _, r := range s.Rates {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t\n", r.Key(), FormatCents(r.DeductibleCents), FormatCents(r.CollectedCents), r.Count)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%s\t%s\t%d\t\n", FormatCents(s.DeductibleCents), FormatCents(s.CollectedCents), len(s.Lines))
+	fmt.Fprintf(tw, "DUE\t\t%s\t\t\n", FormatCents(s.DueCents))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if flagged := len(s.Flagged()); flagged > 0 {
+		_, err := fmt.Fprintf(w, "\n%d transaction(s) need to be checked\n", flagged)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package report_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/report"
+)
+
+func TestVATReport(t *testing.T) {
+	vat := func(cents int64, rate float64) (*int64, *float64) { return &cents, &rate }
+	at := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	t1 := &qonto.Transaction{ID: "t1", Side: qonto.TransactionSideDebit, AmountCents: 12000, Status: qonto.TransactionStatusCompleted, EmittedAt: at, AttachmentIDs: []string{"a1"}}
+	t1.VATAmountCents, t1.VATRate = vat(2000, 20)
+	t2 := &qonto.Transaction{ID: "t2", Side: qonto.TransactionSideCredit, AmountCents: 60000, Status: qonto.TransactionStatusCompleted, EmittedAt: at}
+	t2.VATAmountCents, t2.VATRate = vat(10000, 20)
+	t3 := &qonto.Transaction{ID: "t3", Side: qonto.TransactionSideDebit, AmountCents: 1055, Status: qonto.TransactionStatusCompleted, EmittedAt: at, AttachmentRequired: true}
+	t3.VATAmountCents, t3.VATRate = vat(55, 5.5)
+	t4 := &qonto.Transaction{ID: "t4", Side: qonto.TransactionSideDebit, AmountCents: 5000, Status: qonto.TransactionStatusCompleted, EmittedAt: at}
+	outside := &qonto.Transaction{ID: "t5", Side: qonto.TransactionSideDebit, AmountCents: 5000, Status: qonto.TransactionStatusCompleted, EmittedAt: at.AddDate(0, 1, 0)}
+
+	s, err := report.VATReport([]*qonto.Transaction{t1, t2, t3, t4, outside}, report.VATOptions{
+		From: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("report.VATReport() failed: %v", err)
+	}
+	if s.DeductibleCents != 2055 || s.CollectedCents != 10000 || s.DueCents != 7945 {
+		t.Errorf("s == %+v; want 20.55 deductible, 100.00 collected, 79.45 due", s)
+	}
+	if len(s.Lines) != 4 {
+		t.Errorf("len(s.Lines) == %d; want %d", len(s.Lines), 4)
+	}
+	flagged := s.Flagged()
+	if len(flagged) != 2 || flagged[0].Transaction.ID != "t3" || flagged[1].Transaction.ID != "t4" {
+		t.Errorf("s.Flagged() == %+v; want t3 and t4", flagged)
+	}
+
+	var buf bytes.Buffer
+	if err = s.WriteCSV(&buf); err != nil {
+		t.Fatalf("s.WriteCSV() failed: %v", err)
+	}
+	want := "vat_rate,deductible_cents,collected_cents,debit_base_cents,credit_base_cents,count\n" +
+		"5.5,55,0,1000,0,1\n" +
+		"20,2000,10000,10000,50000,2\n" +
+		"(none),0,0,5000,0,1\n"
+	if buf.String() != want {
+		t.Errorf("CSV == %q; want %q", buf.String(), want)
+	}
+}
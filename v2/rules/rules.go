@@ -0,0 +1,336 @@
+/*
+Package rules automatically labels and annotates Qonto transactions.
+
+A rule matches transactions on their label, amount, operation type, side, counterparty or
+initiator, and sets labels, a VAT rate or a note on the matching transactions. Rules are
+usually loaded from a YAML or JSON file:
+
+	# rules.yaml
+	- name: trains
+	  when:
+	    label: "^SNCF"
+	    side: debit
+	  then:
+	    label_ids: ["label-travel"]
+	    vat_rate: 10
+
+A rule without criteria is rejected, unless it sets "match_all: true".
+
+Example:
+
+	e, _ := rules.LoadFile("rules.yaml")
+
+	// review the changes
+	for _, p := range e.DryRun(transactions) {
+		fmt.Println(p)
+	}
+
+	// and apply them
+	_, err := e.Apply(ctx, c, transactions)
+*/
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ushu/qonto-go/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrEmptyCondition is returned by NewEngine for the rules without criteria, which would match
+// all the transactions, unless Rule.MatchAll is set.
+var ErrEmptyCondition = errors.New("Rule has no condition")
+
+// Condition lists the criteria a transaction must match. Empty criteria are ignored,
+// so the zero value matches all the transactions.
+type Condition struct {
+	// Label is a regular expression matched against Transaction.Label
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+	// Counterparty matches Transaction.Label (which holds the counterparty name), ignoring case and extra whitespace
+	Counterparty string `json:"counterparty,omitempty" yaml:"counterparty,omitempty"`
+	// MinAmountCents is the minimum amount (inclusive)
+	MinAmountCents *int64 `json:"min_amount_cents,omitempty" yaml:"min_amount_cents,omitempty"`
+	// MaxAmountCents is the maximum amount (inclusive)
+	MaxAmountCents *int64 `json:"max_amount_cents,omitempty" yaml:"max_amount_cents,omitempty"`
+	// OperationTypes lists the accepted operation types
+	OperationTypes []qonto.OperationType `json:"operation_types,omitempty" yaml:"operation_types,omitempty"`
+	// Side is "debit" or "credit"
+	Side qonto.TransactionSide `json:"side,omitempty" yaml:"side,omitempty"`
+	// MemberIDs lists the accepted initiators
+	MemberIDs []string `json:"member_ids,omitempty" yaml:"member_ids,omitempty"`
+}
+
+// Action lists the changes applied to matching transactions.
+type Action struct {
+	// LabelIDs are added to the labels of the transaction
+	LabelIDs []string `json:"label_ids,omitempty" yaml:"label_ids,omitempty"`
+	// VATRate replaces the VAT rate (the VAT amount is computed from the rate)
+	VATRate *float64 `json:"vat_rate,omitempty" yaml:"vat_rate,omitempty"`
+	// Note replaces the note
+	Note *string `json:"note,omitempty" yaml:"note,omitempty"`
+}
+
+// Rule associates a Condition to an Action.
+type Rule struct {
+	Name string    `json:"name" yaml:"name"`
+	When Condition `json:"when" yaml:"when"`
+	Then Action    `json:"then" yaml:"then"`
+	// Stop prevents the following rules from being evaluated when this rule matches
+	Stop bool `json:"stop,omitempty" yaml:"stop,omitempty"`
+	// MatchAll must be set on the rules applying to all the transactions (with an empty When)
+	MatchAll bool `json:"match_all,omitempty" yaml:"match_all,omitempty"`
+}
+
+// Updater applies the changes to the transactions. It is implemented by *qonto.Client.
+type Updater interface {
+	UpdateTransactionContext(ctx context.Context, id string, update *qonto.TransactionUpdate) (*qonto.Transaction, error)
+	UpdateTransactionLabelsContext(ctx context.Context, id string, labelIDs []string) (*qonto.Transaction, error)
+}
+
+// Proposal describes the changes the rules bring to a transaction.
+// Fields are nil when unchanged.
+type Proposal struct {
+	Transaction    *qonto.Transaction
+	Rules          []string // names of the matching rules
+	LabelIDs       []string // the new labels (existing labels included)
+	VATRate        *float64
+	VATAmountCents *int64
+	Note           *string
+}
+
+func (p *Proposal) String() string {
+	var changes []string
+	if p.LabelIDs != nil {
+		changes = append(changes, fmt.Sprintf("labels=%s", strings.Join(p.LabelIDs, ",")))
+	}
+	if p.VATRate != nil {
+		changes = append(changes, fmt.Sprintf("vat_rate=%v vat_amount_cents=%d", *p.VATRate, *p.VATAmountCents))
+	}
+	if p.Note != nil {
+		changes = append(changes, fmt.Sprintf("note=%q", *p.Note))
+	}
+	return fmt.Sprintf("%s [%s]: %s", p.Transaction.ID, strings.Join(p.Rules, ","), strings.Join(changes, " "))
+}
+
+// compiledRule is a Rule with its regular expression compiled.
+type compiledRule struct {
+	Rule
+	label *regexp.Regexp
+}
+
+// Engine evaluates a list of rules, in order: when several rules set the VAT rate or the note,
+// the last matching rule wins.
+type Engine struct {
+	rules []compiledRule
+}
+
+// NewEngine compiles the rules.
+func NewEngine(rules []Rule) (*Engine, error) {
+	e := &Engine{rules: make([]compiledRule, len(rules))}
+	for i, r := range rules {
+		if r.When.empty() && !r.MatchAll {
+			return nil, fmt.Errorf("%w: %q (set match_all to apply it to all the transactions)", ErrEmptyCondition, r.Name)
+		}
+		e.rules[i].Rule = r
+		if r.When.Label != "" {
+			re, err := regexp.Compile(r.When.Label)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid label expression in rule %q: %w", r.Name, err)
+			}
+			e.rules[i].label = re
+		}
+	}
+	return e, nil
+}
+
+// Load reads rules from r, in JSON or YAML (JSON is valid YAML).
+func Load(r io.Reader) (*Engine, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err = yaml.UnmarshalStrict(buf, &rules); err != nil {
+		return nil, fmt.Errorf("Could not read rules: %w", err)
+	}
+	return NewEngine(rules)
+}
+
+// LoadFile reads rules from a ".json", ".yaml" or ".yml" file.
+func LoadFile(path string) (*Engine, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&rules)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(buf, &rules)
+	default:
+		return nil, fmt.Errorf("Unknown rules format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not read rules from %s: %w", path, err)
+	}
+	return NewEngine(rules)
+}
+
+// Evaluate returns the changes the rules bring to t, or nil if nothing changes.
+func (e *Engine) Evaluate(t *qonto.Transaction) *Proposal {
+	p := &Proposal{Transaction: t}
+	labels := append([]string(nil), t.LabelIDs...)
+	changed := false
+	var vatRate *float64
+	var note *string
+	for _, r := range e.rules {
+		if !r.match(t) {
+			continue
+		}
+		p.Rules = append(p.Rules, r.Name)
+		for _, id := range r.Then.LabelIDs {
+			if !contains(labels, id) {
+				labels = append(labels, id)
+				p.LabelIDs = labels
+				changed = true
+			}
+		}
+		if r.Then.VATRate != nil {
+			vatRate = r.Then.VATRate
+		}
+		if r.Then.Note != nil {
+			note = r.Then.Note
+		}
+		if r.Stop {
+			break
+		}
+	}
+	// the VAT rate and note are compared once the last matching rule is known
+	if vatRate != nil && (t.VATRate == nil || *t.VATRate != *vatRate) {
+		rate := *vatRate
+		vat := VATAmountCents(t.AmountCents, rate)
+		p.VATRate, p.VATAmountCents = &rate, &vat
+		changed = true
+	}
+	if note != nil && (t.Note == nil || *t.Note != *note) {
+		n := *note
+		p.Note = &n
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return p
+}
+
+// DryRun returns the changes the rules would bring to the transactions, without applying them.
+func (e *Engine) DryRun(transactions []*qonto.Transaction) []*Proposal {
+	var res []*Proposal
+	for _, t := range transactions {
+		if p := e.Evaluate(t); p != nil {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Apply evaluates the rules and sends the changes to Qonto with u.
+// It returns the applied proposals, and stops on the first error.
+func (e *Engine) Apply(ctx context.Context, u Updater, transactions []*qonto.Transaction) ([]*Proposal, error) {
+	var applied []*Proposal
+	for _, p := range e.DryRun(transactions) {
+		if p.LabelIDs != nil {
+			if _, err := u.UpdateTransactionLabelsContext(ctx, p.Transaction.ID, p.LabelIDs); err != nil {
+				return applied, err
+			}
+		}
+		if p.VATRate != nil || p.Note != nil {
+			update := &qonto.TransactionUpdate{
+				Note:           p.Note,
+				VATRate:        p.VATRate,
+				VATAmountCents: p.VATAmountCents,
+			}
+			if _, err := u.UpdateTransactionContext(ctx, p.Transaction.ID, update); err != nil {
+				return applied, err
+			}
+		}
+		applied = append(applied, p)
+	}
+	return applied, nil
+}
+
+// VATAmountCents computes the VAT included in amountCents for the VAT rate (in percents),
+// rounded to the nearest cent.
+func VATAmountCents(amountCents int64, rate float64) int64 {
+	return int64(math.Round(float64(amountCents) * rate / (100 + rate)))
+}
+
+func (r *compiledRule) match(t *qonto.Transaction) bool {
+	c := &r.When
+	label := ""
+	if t.Label != nil {
+		label = *t.Label
+	}
+	if r.label != nil && !r.label.MatchString(label) {
+		return false
+	}
+	if c.Counterparty != "" && normalize(c.Counterparty) != normalize(label) {
+		return false
+	}
+	if c.MinAmountCents != nil && t.AmountCents < *c.MinAmountCents {
+		return false
+	}
+	if c.MaxAmountCents != nil && t.AmountCents > *c.MaxAmountCents {
+		return false
+	}
+	if len(c.OperationTypes) > 0 {
+		found := false
+		for _, o := range c.OperationTypes {
+			if o == t.OperationType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.Side != "" && c.Side != t.Side {
+		return false
+	}
+	if len(c.MemberIDs) > 0 && (t.InitiatorID == nil || !contains(c.MemberIDs, *t.InitiatorID)) {
+		return false
+	}
+	return true
+}
+
+func (c *Condition) empty() bool {
+	return c.Label == "" && c.Counterparty == "" && c.MinAmountCents == nil && c.MaxAmountCents == nil &&
+		len(c.OperationTypes) == 0 && c.Side == "" && len(c.MemberIDs) == 0
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
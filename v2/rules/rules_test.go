@@ -0,0 +1,194 @@
+package rules_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/rules"
+)
+
+const testRules = `
+- name: trains
+  when:
+    label: "^SNCF"
+    side: debit
+    operation_types: [card]
+  then:
+    label_ids: [travel]
+    vat_rate: 10
+- name: small
+  when:
+    max_amount_cents: 1000
+  then:
+    note: "small expense"
+  stop: true
+- name: never
+  when:
+    max_amount_cents: 1000
+  then:
+    note: "not reached because of stop"
+`
+
+// fakeUpdater records the updates.
+type fakeUpdater struct {
+	labels  map[string][]string
+	updates map[string]*qonto.TransactionUpdate
+}
+
+func (u *fakeUpdater) UpdateTransactionContext(ctx context.Context, id string, update *qonto.TransactionUpdate) (*qonto.Transaction, error) {
+	u.updates[id] = update
+	return &qonto.Transaction{ID: id}, nil
+}
+
+func (u *fakeUpdater) UpdateTransactionLabelsContext(ctx context.Context, id string, labelIDs []string) (*qonto.Transaction, error) {
+	u.labels[id] = labelIDs
+	return &qonto.Transaction{ID: id}, nil
+}
+
+func transaction(id, label string, cents int64) *qonto.Transaction {
+	return &qonto.Transaction{
+		ID:            id,
+		Label:         &label,
+		AmountCents:   cents,
+		Side:          qonto.TransactionSideDebit,
+		OperationType: qonto.OperationTypeCard,
+	}
+}
+
+func TestEngine(t *testing.T) {
+	e, err := rules.Load(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("rules.Load() failed: %v", err)
+	}
+
+	train := transaction("t1", "SNCF PARIS", 5500)
+	coffee := transaction("t2", "COFFEE SHOP", 350)
+	already := transaction("t3", "SNCF LYON", 800)
+	already.LabelIDs = []string{"travel"}
+
+	proposals := e.DryRun([]*qonto.Transaction{train, coffee, already})
+	if len(proposals) != 3 {
+		t.Fatalf("len(proposals) == %d; want %d", len(proposals), 3)
+	}
+
+	p := proposals[0]
+	if len(p.LabelIDs) != 1 || p.LabelIDs[0] != "travel" || *p.VATRate != 10 || *p.VATAmountCents != 500 || p.Note != nil {
+		t.Errorf("proposals[0] == %s; want travel label and 10%% VAT", p)
+	}
+	p = proposals[1]
+	if p.LabelIDs != nil || p.VATRate != nil || *p.Note != "small expense" {
+		t.Errorf("proposals[1] == %s; want a note only", p)
+	}
+	p = proposals[2]
+	if p.LabelIDs != nil || *p.VATRate != 10 || *p.Note != "small expense" {
+		t.Errorf("proposals[2] == %s; want VAT and note, labels unchanged", p)
+	}
+	if len(p.Rules) != 2 {
+		t.Errorf("proposals[2].Rules == %v; want [trains small]", p.Rules)
+	}
+
+	// apply the changes
+	u := &fakeUpdater{labels: map[string][]string{}, updates: map[string]*qonto.TransactionUpdate{}}
+	applied, err := e.Apply(context.Background(), u, []*qonto.Transaction{train, coffee})
+	if err != nil {
+		t.Fatalf("e.Apply() failed: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("len(applied) == %d; want %d", len(applied), 2)
+	}
+	if len(u.labels) != 1 || u.labels["t1"][0] != "travel" {
+		t.Errorf("u.labels == %v; want labels for t1 only", u.labels)
+	}
+	if len(u.updates) != 2 || *u.updates["t1"].VATRate != 10 || *u.updates["t2"].Note != "small expense" {
+		t.Errorf("u.updates == %v; want VAT for t1 and a note for t2", u.updates)
+	}
+}
+
+func TestLoad_InvalidExpression(t *testing.T) {
+	_, err := rules.Load(strings.NewReader(`[{"name": "bad", "when": {"label": "("}}]`))
+	if err == nil {
+		t.Errorf("rules.Load() should fail on invalid regular expressions")
+	}
+}
+
+func TestLoadFile_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	_ = ioutil.WriteFile(path, []byte(`[{"name": "typo", "when": {"lable": "^SNCF"}, "then": {"note": "train"}}]`), 0600)
+	if _, err := rules.LoadFile(path); err == nil {
+		t.Errorf("rules.LoadFile() should fail on unknown fields")
+	}
+}
+
+func TestNewEngine_EmptyCondition(t *testing.T) {
+	note := "all"
+	_, err := rules.NewEngine([]rules.Rule{{Name: "all", Then: rules.Action{Note: &note}}})
+	if !errors.Is(err, rules.ErrEmptyCondition) {
+		t.Errorf("rules.NewEngine() == %v; want ErrEmptyCondition", err)
+	}
+	e, err := rules.NewEngine([]rules.Rule{{Name: "all", Then: rules.Action{Note: &note}, MatchAll: true}})
+	if err != nil {
+		t.Fatalf("rules.NewEngine() failed: %v", err)
+	}
+	if p := e.Evaluate(&qonto.Transaction{ID: "t1"}); p == nil || *p.Note != "all" {
+		t.Errorf("e.Evaluate() == %v; want a note", p)
+	}
+}
+
+func TestEngine_LastMatchWins(t *testing.T) {
+	e, err := rules.Load(strings.NewReader(`
+- name: travel
+  when:
+    label: "^SNCF"
+  then:
+    vat_rate: 10
+    note: "train"
+- name: exports
+  when:
+    counterparty: "sncf  international"
+  then:
+    vat_rate: 0
+    note: "export"
+`))
+	if err != nil {
+		t.Fatalf("rules.Load() failed: %v", err)
+	}
+
+	// the last rule restores the current VAT rate and note: nothing changes
+	export := transaction("t1", "SNCF International", 5500)
+	rate, note := 0.0, "export"
+	export.VATRate, export.Note = &rate, &note
+	if p := e.Evaluate(export); p != nil {
+		t.Errorf("e.Evaluate() == %s; want nil", p)
+	}
+
+	// and it wins over the first one otherwise
+	export.VATRate, export.Note = nil, nil
+	if p := e.Evaluate(export); p == nil || *p.VATRate != 0 || *p.Note != "export" {
+		t.Errorf("e.Evaluate() == %v; want the VAT rate and note of the last rule", p)
+	}
+	if p := e.Evaluate(transaction("t2", "SNCF Paris", 5500)); p == nil || *p.VATRate != 10 || *p.Note != "train" {
+		t.Errorf("e.Evaluate() == %v; want the VAT rate and note of the first rule", p)
+	}
+}
+
+func TestVATAmountCents(t *testing.T) {
+	tests := []struct {
+		amount int64
+		rate   float64
+		want   int64
+	}{
+		{12000, 20, 2000},
+		{1055, 5.5, 55},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := rules.VATAmountCents(tt.amount, tt.rate); got != tt.want {
+			t.Errorf("rules.VATAmountCents(%d, %v) == %d; want %d", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package qonto
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// flight is a GET request in progress, shared by the callers asking for the same URL.
+type flight struct {
+	done    chan struct{} // closed once body and err are set
+	body    json.RawMessage
+	err     error
+	waiters int                // the callers still waiting, guarded by Client.flightsMu
+	cancel  context.CancelFunc // cancels the request when all the callers gave up
+}
+
+// shared sends the GET request req once for all the concurrent callers of the same URL (the
+// requests of a Client share the same credentials), and decodes the response into ref.
+//
+// The request is sent with the context values of the first caller, but it is only cancelled
+// when all the waiting callers gave up: a caller leaving early gets its context error, and
+// does not disturb the others.
+func (c *Client) shared(req *http.Request, ref interface{}) error {
+	key := req.URL.String()
+	ctx := req.Context()
+
+	c.flightsMu.Lock()
+	if c.flights == nil {
+		c.flights = make(map[string]*flight)
+	}
+	f, ok := c.flights[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		c.flights[key] = f
+		go c.fly(key, f, req.WithContext(fctx))
+	}
+	f.waiters++
+	c.flightsMu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return f.err
+		}
+		return decodeResponse(f.body, ref)
+	case <-ctx.Done():
+		c.flightsMu.Lock()
+		f.waiters--
+		if f.waiters == 0 && c.flights[key] == f {
+			delete(c.flights, key) // ⬅︎ the next callers start a new request
+			f.cancel()
+		}
+		c.flightsMu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// fly sends the request of f, and hands the response over to the waiting callers.
+func (c *Client) fly(key string, f *flight, req *http.Request) {
+	f.err = c.observe(req, &f.body)
+
+	c.flightsMu.Lock()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+	c.flightsMu.Unlock()
+	f.cancel()
+	close(f.done)
+}
@@ -0,0 +1,83 @@
+package qonto_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+func TestSingleFlight(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-release:
+			fmt.Fprint(w, `{"organization":{"slug":"acme","bank_accounts":[]}}`)
+		case <-r.Context().Done():
+			close(cancelled)
+		}
+	}))
+	defer srv.Close()
+
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+
+	// the first caller gives up, the others get the shared response
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrganizationContext(ctx)
+		errs <- err
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	orgs := make([]*qonto.Organization, 4)
+	for i := range orgs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			org, err := c.GetOrganizationContext(context.Background())
+			if err != nil {
+				t.Errorf("c.GetOrganizationContext() failed: %v", err)
+			}
+			orgs[i] = org
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("c.GetOrganizationContext() == %v; want %v", err, context.Canceled)
+	}
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("requests == %d; want 1", n)
+	}
+	if orgs[0] == nil || orgs[0] == orgs[1] || orgs[0].Slug != "acme" {
+		t.Errorf("orgs == %v; want distinct organizations", orgs)
+	}
+
+	// the request is cancelled once every caller gave up
+	release = make(chan struct{})
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetOrganizationContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("c.GetOrganizationContext() == %v; want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the upstream request was not cancelled")
+	}
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// jsonBackend stores each bucket as an indented JSON object in its own file.
+type jsonBackend struct {
+	dir  string
+	recs records
+}
+
+// OpenJSON opens (or creates) a Store that keeps one JSON file per collection
+// ("transactions.json", "labels.json" etc.) in the directory dir.
+//
+// Every change rewrites the file of the collection, so this Store is better suited to
+// small organizations or to data meant to be inspected by hand.
+func OpenJSON(dir string) (Store, error) {
+	return newStore(&jsonBackend{dir: dir})
+}
+
+func (b *jsonBackend) load() (records, error) {
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return nil, err
+	}
+	b.recs = make(records)
+	buckets := []string{bucketWatermarks, bucketTransactions, bucketLabels, bucketMemberships, bucketAttachments}
+	for _, bucket := range buckets {
+		values := make(map[string]json.RawMessage)
+		buf, err := ioutil.ReadFile(b.filename(bucket))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err = json.Unmarshal(buf, &values); err != nil {
+				return nil, err
+			}
+		}
+		b.recs[bucket] = values
+	}
+	return b.recs, nil
+}
+
+func (b *jsonBackend) filename(bucket string) string {
+	return filepath.Join(b.dir, bucket+".json")
+}
+
+// write replaces the file of the bucket, going through a temporary file so that a crash
+// never leaves a partially written file.
+func (b *jsonBackend) write(bucket string) error {
+	buf, err := json.MarshalIndent(b.recs[bucket], "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := b.filename(bucket) + ".tmp"
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.filename(bucket))
+}
+
+func (b *jsonBackend) put(bucket, key string, value json.RawMessage) error {
+	b.recs[bucket][key] = value
+	return b.write(bucket)
+}
+
+func (b *jsonBackend) delete(bucket, key string) error {
+	delete(b.recs[bucket], key)
+	return b.write(bucket)
+}
+
+func (b *jsonBackend) close() error {
+	return nil
+}
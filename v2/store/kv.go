@@ -0,0 +1,138 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// kvRecord is a single line of the key-value log.
+type kvRecord struct {
+	Bucket  string          `json:"b"`
+	Key     string          `json:"k"`
+	Value   json.RawMessage `json:"v,omitempty"`
+	Deleted bool            `json:"d,omitempty"`
+}
+
+// kvBackend is an embedded key-value store: every change is appended to a single file
+// (one JSON record per line), and the file is replayed when the store is opened.
+type kvBackend struct {
+	path string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// OpenKV opens (or creates) an embedded key-value Store in the file at path.
+//
+// The file is compacted when opened, so that it only holds the latest value of each key.
+func OpenKV(path string) (Store, error) {
+	return newStore(&kvBackend{path: path})
+}
+
+func (b *kvBackend) load() (records, error) {
+	recs := make(records)
+
+	// replay the log
+	f, err := os.Open(b.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		dec := json.NewDecoder(bufio.NewReader(f))
+		for {
+			var r kvRecord
+			err = dec.Decode(&r)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// a truncated last line means the process died while writing it
+				if err == io.ErrUnexpectedEOF {
+					break
+				}
+				_ = f.Close()
+				return nil, fmt.Errorf("Corrupted key-value store %s: %w", b.path, err)
+			}
+			if recs[r.Bucket] == nil {
+				recs[r.Bucket] = make(map[string]json.RawMessage)
+			}
+			if r.Deleted {
+				delete(recs[r.Bucket], r.Key)
+			} else {
+				recs[r.Bucket][r.Key] = r.Value
+			}
+		}
+		_ = f.Close()
+	}
+
+	// compact it, and reopen it for appending
+	if err = b.compact(recs); err != nil {
+		return nil, err
+	}
+	b.f, err = os.OpenFile(b.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	b.w = bufio.NewWriter(b.f)
+	return recs, nil
+}
+
+// compact rewrites the whole log, with only the live values.
+func (b *kvBackend) compact(recs records) error {
+	tmp := b.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for bucket, values := range recs {
+		for k, v := range values {
+			if err = enc.Encode(kvRecord{Bucket: bucket, Key: k, Value: v}); err != nil {
+				_ = f.Close()
+				return err
+			}
+		}
+	}
+	if err = w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
+
+func (b *kvBackend) append(r kvRecord) error {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err = b.w.Write(append(buf, '\n')); err != nil {
+		return err
+	}
+	return b.w.Flush()
+}
+
+func (b *kvBackend) put(bucket, key string, value json.RawMessage) error {
+	return b.append(kvRecord{Bucket: bucket, Key: key, Value: value})
+}
+
+func (b *kvBackend) delete(bucket, key string) error {
+	return b.append(kvRecord{Bucket: bucket, Key: key, Deleted: true})
+}
+
+func (b *kvBackend) close() error {
+	if err := b.w.Flush(); err != nil {
+		_ = b.f.Close()
+		return err
+	}
+	return b.f.Close()
+}
@@ -0,0 +1,382 @@
+/*
+Package store keeps a local copy of the data downloaded from Qonto.
+
+A Store holds transactions, labels, memberships, attachments metadata and the sync
+watermarks. It implements sync.State, so it can be passed directly to sync.NewEngine, and
+offers simple queries so that reports can be built without calling the API.
+
+Three implementations are provided:
+
+	NewMemoryStore()    // nothing is persisted
+	OpenKV("qonto.db")  // an embedded key-value store, in a single append-only file
+	OpenJSON("./data")  // a directory of JSON files, easy to inspect or commit
+
+Example:
+
+	s, _ := store.OpenKV("qonto.db")
+	defer s.Close()
+
+	e := sync.NewEngine(c, s)
+	_, _ = e.Sync(ctx, ba)
+
+	debits, _ := s.Transactions(store.Query{Side: qonto.TransactionSideDebit, From: from, To: to})
+*/
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	gosync "sync"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// Store persists the data synchronized from Qonto.
+// All the implementations are safe for concurrent use.
+type Store interface {
+	// Watermark returns the sync watermark of the bank account (or the zero time)
+	Watermark(account string) (time.Time, error)
+	// SetWatermark stores the sync watermark of the bank account
+	SetWatermark(account string, watermark time.Time) error
+
+	// Transaction returns the transaction with the provided id, or nil when unknown
+	Transaction(id string) (*qonto.Transaction, error)
+	// TransactionIDs lists the IDs of all the transactions of the bank account
+	TransactionIDs(account string) ([]string, error)
+	// PutTransaction inserts or replaces a transaction of the bank account
+	PutTransaction(account string, t *qonto.Transaction) error
+	// DeleteTransaction removes a transaction of the bank account
+	DeleteTransaction(account string, id string) error
+	// Transactions lists the transactions matching q, sorted by emission date
+	Transactions(q Query) ([]*qonto.Transaction, error)
+
+	// PutLabels inserts or replaces labels
+	PutLabels(labels []qonto.Label) error
+	// Labels lists all the known labels
+	Labels() ([]qonto.Label, error)
+	// PutMemberships inserts or replaces memberships
+	PutMemberships(memberships []qonto.Membership) error
+	// Memberships lists all the known memberships
+	Memberships() ([]qonto.Membership, error)
+	// PutAttachment inserts or replaces the metadata of an attachment
+	PutAttachment(a *qonto.Attachment) error
+	// Attachment returns the metadata of an attachment, or nil when unknown
+	Attachment(id string) (*qonto.Attachment, error)
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// Query filters the transactions returned by Store.Transactions.
+// The zero value matches all the transactions.
+type Query struct {
+	Account  string                    // (optional) the slug of the bank account
+	From     time.Time                 // (optional) minimum emission date (inclusive)
+	To       time.Time                 // (optional) maximum emission date (exclusive)
+	LabelID  string                    // (optional) only transactions with this label
+	Side     qonto.TransactionSide     // (optional) only debits or credits
+	Statuses []qonto.TransactionStatus // (optional) only transactions with one of these statuses
+}
+
+// Match reports whether the transaction t of the provided bank account matches q.
+func (q *Query) Match(account string, t *qonto.Transaction) bool {
+	if q.Account != "" && q.Account != account {
+		return false
+	}
+	if !q.From.IsZero() && t.EmittedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !t.EmittedAt.Before(q.To) {
+		return false
+	}
+	if q.Side != "" && q.Side != t.Side {
+		return false
+	}
+	if q.LabelID != "" && !contains(t.LabelIDs, q.LabelID) {
+		return false
+	}
+	if len(q.Statuses) > 0 {
+		found := false
+		for _, s := range q.Statuses {
+			if s == t.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// names of the collections ("buckets") handled by the backends
+const (
+	bucketWatermarks   = "watermarks"
+	bucketTransactions = "transactions"
+	bucketLabels       = "labels"
+	bucketMemberships  = "memberships"
+	bucketAttachments  = "attachments"
+)
+
+// records holds raw JSON values by bucket and key.
+type records map[string]map[string]json.RawMessage
+
+// backend persists the JSON-encoded values of a store.
+type backend interface {
+	load() (records, error)
+	put(bucket, key string, value json.RawMessage) error
+	delete(bucket, key string) error
+	close() error
+}
+
+// storedTransaction is the value persisted in the "transactions" bucket.
+type storedTransaction struct {
+	Account     string             `json:"account"`
+	Transaction *qonto.Transaction `json:"transaction"`
+}
+
+// store indexes all the data in memory, and forwards the changes to the backend.
+type store struct {
+	mu           gosync.RWMutex
+	b            backend
+	watermarks   map[string]time.Time
+	transactions map[string]storedTransaction
+	labels       map[string]qonto.Label
+	memberships  map[string]qonto.Membership
+	attachments  map[string]*qonto.Attachment
+}
+
+// NewMemoryStore creates a Store that does not persist anything.
+func NewMemoryStore() Store {
+	s, _ := newStore(nil)
+	return s
+}
+
+func newStore(b backend) (*store, error) {
+	s := &store{
+		b:            b,
+		watermarks:   make(map[string]time.Time),
+		transactions: make(map[string]storedTransaction),
+		labels:       make(map[string]qonto.Label),
+		memberships:  make(map[string]qonto.Membership),
+		attachments:  make(map[string]*qonto.Attachment),
+	}
+	if b == nil {
+		return s, nil
+	}
+
+	recs, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range recs[bucketWatermarks] {
+		var w time.Time
+		if err = json.Unmarshal(v, &w); err != nil {
+			return nil, err
+		}
+		s.watermarks[k] = w
+	}
+	for k, v := range recs[bucketTransactions] {
+		var t storedTransaction
+		if err = json.Unmarshal(v, &t); err != nil {
+			return nil, err
+		}
+		s.transactions[k] = t
+	}
+	for k, v := range recs[bucketLabels] {
+		var l qonto.Label
+		if err = json.Unmarshal(v, &l); err != nil {
+			return nil, err
+		}
+		s.labels[k] = l
+	}
+	for k, v := range recs[bucketMemberships] {
+		var m qonto.Membership
+		if err = json.Unmarshal(v, &m); err != nil {
+			return nil, err
+		}
+		s.memberships[k] = m
+	}
+	for k, v := range recs[bucketAttachments] {
+		var a qonto.Attachment
+		if err = json.Unmarshal(v, &a); err != nil {
+			return nil, err
+		}
+		s.attachments[k] = &a
+	}
+	return s, nil
+}
+
+// persist encodes and sends v to the backend (if any).
+// It must be called with the lock held.
+func (s *store) persist(bucket, key string, v interface{}) error {
+	if s.b == nil {
+		return nil
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.b.put(bucket, key, buf)
+}
+
+func (s *store) Watermark(account string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.watermarks[account], nil
+}
+
+func (s *store) SetWatermark(account string, watermark time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.persist(bucketWatermarks, account, watermark); err != nil {
+		return err
+	}
+	s.watermarks[account] = watermark
+	return nil
+}
+
+func (s *store) Transaction(id string) (*qonto.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.transactions[id].Transaction, nil
+}
+
+func (s *store) TransactionIDs(account string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for id, t := range s.transactions {
+		if t.Account == account {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *store) PutTransaction(account string, t *qonto.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := storedTransaction{Account: account, Transaction: t}
+	if err := s.persist(bucketTransactions, t.ID, st); err != nil {
+		return err
+	}
+	s.transactions[t.ID] = st
+	return nil
+}
+
+func (s *store) DeleteTransaction(account string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.transactions[id]; !ok || t.Account != account {
+		return nil
+	}
+	if s.b != nil {
+		if err := s.b.delete(bucketTransactions, id); err != nil {
+			return err
+		}
+	}
+	delete(s.transactions, id)
+	return nil
+}
+
+func (s *store) Transactions(q Query) ([]*qonto.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var res []*qonto.Transaction
+	for _, t := range s.transactions {
+		if q.Match(t.Account, t.Transaction) {
+			res = append(res, t.Transaction)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].EmittedAt.Equal(res[j].EmittedAt) {
+			return res[i].ID < res[j].ID
+		}
+		return res[i].EmittedAt.Before(res[j].EmittedAt)
+	})
+	return res, nil
+}
+
+func (s *store) PutLabels(labels []qonto.Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range labels {
+		if err := s.persist(bucketLabels, l.ID, l); err != nil {
+			return err
+		}
+		s.labels[l.ID] = l
+	}
+	return nil
+}
+
+func (s *store) Labels() ([]qonto.Label, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	labels := make([]qonto.Label, 0, len(s.labels))
+	for _, l := range s.labels {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+	return labels, nil
+}
+
+func (s *store) PutMemberships(memberships []qonto.Membership) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range memberships {
+		if err := s.persist(bucketMemberships, m.ID, m); err != nil {
+			return err
+		}
+		s.memberships[m.ID] = m
+	}
+	return nil
+}
+
+func (s *store) Memberships() ([]qonto.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	memberships := make([]qonto.Membership, 0, len(s.memberships))
+	for _, m := range s.memberships {
+		memberships = append(memberships, m)
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].ID < memberships[j].ID })
+	return memberships, nil
+}
+
+func (s *store) PutAttachment(a *qonto.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.persist(bucketAttachments, a.ID, a); err != nil {
+		return err
+	}
+	s.attachments[a.ID] = a
+	return nil
+}
+
+func (s *store) Attachment(id string) (*qonto.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.attachments[id], nil
+}
+
+func (s *store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.b == nil {
+		return nil
+	}
+	return s.b.close()
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
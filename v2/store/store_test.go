@@ -0,0 +1,132 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/store"
+	"github.com/ushu/qonto-go/v2/sync"
+)
+
+// a Store can be used as the State of a sync.Engine
+var _ sync.State = store.NewMemoryStore()
+
+func testTransactions() []*qonto.Transaction {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	return []*qonto.Transaction{
+		{ID: "t1", Side: qonto.TransactionSideDebit, Status: qonto.TransactionStatusCompleted, EmittedAt: day(1), LabelIDs: []string{"l1"}},
+		{ID: "t2", Side: qonto.TransactionSideCredit, Status: qonto.TransactionStatusCompleted, EmittedAt: day(2)},
+		{ID: "t3", Side: qonto.TransactionSideDebit, Status: qonto.TransactionStatusPending, EmittedAt: day(3), LabelIDs: []string{"l1", "l2"}},
+	}
+}
+
+func fill(t *testing.T, s store.Store) {
+	t.Helper()
+	for _, tr := range testTransactions() {
+		if err := s.PutTransaction("account", tr); err != nil {
+			t.Fatalf("s.PutTransaction() failed: %v", err)
+		}
+	}
+	if err := s.DeleteTransaction("account", "t2"); err != nil {
+		t.Fatalf("s.DeleteTransaction() failed: %v", err)
+	}
+	if err := s.SetWatermark("account", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("s.SetWatermark() failed: %v", err)
+	}
+	if err := s.PutLabels([]qonto.Label{{ID: "l1", Name: "Travel"}}); err != nil {
+		t.Fatalf("s.PutLabels() failed: %v", err)
+	}
+}
+
+func check(t *testing.T, s store.Store) {
+	t.Helper()
+	ids, _ := s.TransactionIDs("account")
+	if len(ids) != 2 || ids[0] != "t1" || ids[1] != "t3" {
+		t.Errorf("s.TransactionIDs() == %v; want [t1 t3]", ids)
+	}
+	w, _ := s.Watermark("account")
+	if want := time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC); !w.Equal(want) {
+		t.Errorf("s.Watermark() == %v; want %v", w, want)
+	}
+	labels, _ := s.Labels()
+	if len(labels) != 1 || labels[0].Name != "Travel" {
+		t.Errorf("s.Labels() == %v; want [Travel]", labels)
+	}
+}
+
+func TestStore_Transactions(t *testing.T) {
+	s := store.NewMemoryStore()
+	fill(t, s)
+
+	tests := []struct {
+		q    store.Query
+		want []string
+	}{
+		{store.Query{}, []string{"t1", "t3"}},
+		{store.Query{Account: "other"}, nil},
+		{store.Query{From: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}, []string{"t3"}},
+		{store.Query{To: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)}, []string{"t1"}},
+		{store.Query{LabelID: "l2"}, []string{"t3"}},
+		{store.Query{Statuses: []qonto.TransactionStatus{qonto.TransactionStatusCompleted}}, []string{"t1"}},
+		{store.Query{Side: qonto.TransactionSideCredit}, nil},
+	}
+	for _, tt := range tests {
+		res, err := s.Transactions(tt.q)
+		if err != nil {
+			t.Fatalf("s.Transactions(%+v) failed: %v", tt.q, err)
+		}
+		var ids []string
+		for _, tr := range res {
+			ids = append(ids, tr.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("s.Transactions(%+v) == %v; want %v", tt.q, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("s.Transactions(%+v) == %v; want %v", tt.q, ids, tt.want)
+			}
+		}
+	}
+}
+
+func TestOpenKV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qonto.db")
+	s, err := store.OpenKV(path)
+	if err != nil {
+		t.Fatalf("store.OpenKV() failed: %v", err)
+	}
+	fill(t, s)
+	if err = s.Close(); err != nil {
+		t.Fatalf("s.Close() failed: %v", err)
+	}
+
+	s, err = store.OpenKV(path)
+	if err != nil {
+		t.Fatalf("store.OpenKV() failed: %v", err)
+	}
+	defer s.Close()
+	check(t, s)
+}
+
+func TestOpenJSON(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.OpenJSON(dir)
+	if err != nil {
+		t.Fatalf("store.OpenJSON() failed: %v", err)
+	}
+	fill(t, s)
+	if err = s.Close(); err != nil {
+		t.Fatalf("s.Close() failed: %v", err)
+	}
+
+	s, err = store.OpenJSON(dir)
+	if err != nil {
+		t.Fatalf("store.OpenJSON() failed: %v", err)
+	}
+	defer s.Close()
+	check(t, s)
+}
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	gosync "sync"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// MemoryState is a State kept in memory, mostly useful for tests and short-lived processes.
+// It is safe for concurrent use.
+type MemoryState struct {
+	mu           gosync.Mutex
+	watermarks   map[string]time.Time
+	transactions map[string]*qonto.Transaction
+	accounts     map[string]map[string]bool // account slug ➡︎ set of transaction IDs
+}
+
+// NewMemoryState creates an empty MemoryState.
+func NewMemoryState() *MemoryState {
+	return &MemoryState{
+		watermarks:   make(map[string]time.Time),
+		transactions: make(map[string]*qonto.Transaction),
+		accounts:     make(map[string]map[string]bool),
+	}
+}
+
+// Watermark returns the watermark stored for account, or the zero time.
+func (s *MemoryState) Watermark(account string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watermarks[account], nil
+}
+
+// SetWatermark stores the watermark for account.
+func (s *MemoryState) SetWatermark(account string, watermark time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watermarks[account] = watermark
+	return nil
+}
+
+// Transaction returns the known transaction with the provided id, or nil.
+func (s *MemoryState) Transaction(id string) (*qonto.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transactions[id], nil
+}
+
+// TransactionIDs lists the IDs of the known transactions of account.
+func (s *MemoryState) TransactionIDs(account string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.accounts[account]))
+	for id := range s.accounts[account] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// PutTransaction stores (or replaces) the transaction t.
+func (s *MemoryState) PutTransaction(account string, t *qonto.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions[t.ID] = t
+	if s.accounts[account] == nil {
+		s.accounts[account] = make(map[string]bool)
+	}
+	s.accounts[account][t.ID] = true
+	return nil
+}
+
+// DeleteTransaction forgets the transaction with the provided id.
+func (s *MemoryState) DeleteTransaction(account string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.transactions, id)
+	delete(s.accounts[account], id)
+	return nil
+}
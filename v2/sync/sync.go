@@ -0,0 +1,240 @@
+/*
+Package sync incrementally downloads the transactions of a Qonto bank account.
+
+The Engine remembers, for each bank account, the most recent "updated_at" value it has
+seen (the watermark) and only asks Qonto for the transactions updated since then.
+Every downloaded transaction is compared with the known version, and the differences
+are reported to the OnChange callback as Created, Updated or Removed changes.
+
+Example:
+
+	c := qonto.NewClient("organization-slug", "secret-key", nil)
+	ba, _ := c.GetBankAccount()
+
+	e := sync.NewEngine(c, sync.NewMemoryState())
+	e.OnChange = func(ctx context.Context, ch sync.Change) error {
+		fmt.Printf("%s %s\n", ch.Kind, ch.Transaction.ID)
+		return nil
+	}
+	res, err := e.Sync(context.Background(), ba)
+
+For long-running processes, a Watcher polls Qonto at regular intervals and sends typed
+events (TransactionCreated, TransactionSettled etc.) on a channel.
+*/
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// DefaultOverlap is the default value for Engine.Overlap.
+const DefaultOverlap = 5 * time.Minute
+
+// SortByUpdatedAt is the "sort_by" option used to list transactions in update order.
+var SortByUpdatedAt = "updated_at:asc"
+
+// TransactionLister lists the transactions of a bank account.
+// It is implemented by *qonto.Client.
+type TransactionLister interface {
+	GetAllTransactionsContext(ctx context.Context, bankAccountID, IBAN string, options *qonto.GetTransactionOptions) ([]*qonto.Transaction, error)
+}
+
+// State persists the progress of the Engine between runs.
+//
+// Transaction returns (nil, nil) when the transaction is unknown.
+type State interface {
+	Watermark(account string) (time.Time, error)
+	SetWatermark(account string, watermark time.Time) error
+	Transaction(id string) (*qonto.Transaction, error)
+	TransactionIDs(account string) ([]string, error)
+	PutTransaction(account string, t *qonto.Transaction) error
+	DeleteTransaction(account string, id string) error
+}
+
+// ChangeKind tells what happened to a transaction.
+type ChangeKind int
+
+const (
+	// Created marks a transaction seen for the first time.
+	Created ChangeKind = iota
+	// Updated marks a known transaction which content changed (status, note, labels etc.).
+	Updated
+	// Removed marks a known transaction no longer returned by Qonto.
+	Removed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a single difference between the State and the data returned by Qonto.
+type Change struct {
+	Kind        ChangeKind
+	Account     string             // the slug of the bank account
+	Transaction *qonto.Transaction // the new version (or the removed transaction)
+	Previous    *qonto.Transaction // the known version, nil for Created changes
+}
+
+// ChangeHandler is called for every Change detected by the Engine.
+// Returning an error aborts the sync without moving the watermark.
+type ChangeHandler func(ctx context.Context, ch Change) error
+
+// Result summarizes a call to Sync.
+type Result struct {
+	Created   int
+	Updated   int
+	Removed   int
+	Watermark time.Time // the watermark stored at the end of the sync
+}
+
+// Engine synchronizes the transactions of bank accounts into a State.
+type Engine struct {
+	Client   TransactionLister
+	State    State
+	OnChange ChangeHandler // (optional) called for each detected change
+	// Overlap is substracted from the watermark before calling Qonto, to tolerate clock skew
+	// and transactions committed out of order (NewEngine sets it to DefaultOverlap)
+	Overlap time.Duration
+	// PerPage is the page size used when listing transactions (defaults to the API default)
+	PerPage int
+}
+
+// NewEngine creates an Engine with the default options.
+func NewEngine(client TransactionLister, state State) *Engine {
+	return &Engine{
+		Client:  client,
+		State:   state,
+		Overlap: DefaultOverlap,
+	}
+}
+
+// Sync fetches the transactions updated since the last watermark of ba, and reconciles them with the State.
+func (e *Engine) Sync(ctx context.Context, ba *qonto.BankAccount) (*Result, error) {
+	if ba == nil {
+		return nil, qonto.ErrBankAccountNeeded
+	}
+	watermark, err := e.State.Watermark(ba.Slug)
+	if err != nil {
+		return nil, err
+	}
+	return e.sync(ctx, ba, watermark, false)
+}
+
+// FullSync fetches all the transactions of ba and reconciles them with the State.
+// Unlike Sync, it reports the known transactions that were not returned as Removed.
+func (e *Engine) FullSync(ctx context.Context, ba *qonto.BankAccount) (*Result, error) {
+	if ba == nil {
+		return nil, qonto.ErrBankAccountNeeded
+	}
+	return e.sync(ctx, ba, time.Time{}, true)
+}
+
+func (e *Engine) sync(ctx context.Context, ba *qonto.BankAccount, watermark time.Time, full bool) (*Result, error) {
+	options := &qonto.GetTransactionOptions{SortBy: &SortByUpdatedAt}
+	if !watermark.IsZero() {
+		from := watermark.Add(-e.Overlap)
+		options.UpdatedAtFrom = &from
+	}
+	if e.PerPage > 0 {
+		options.PerPage = &e.PerPage
+	}
+	transactions, err := e.Client.GetAllTransactionsContext(ctx, ba.Slug, ba.IBAN, options)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Watermark: watermark}
+	seen := make(map[string]bool, len(transactions))
+	for _, t := range transactions {
+		seen[t.ID] = true
+		if t.UpdatedAt != nil && t.UpdatedAt.After(res.Watermark) {
+			res.Watermark = *t.UpdatedAt
+		}
+
+		previous, err := e.State.Transaction(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		ch := Change{Kind: Created, Account: ba.Slug, Transaction: t, Previous: previous}
+		if previous != nil {
+			if Equal(previous, t) {
+				continue
+			}
+			ch.Kind = Updated
+		}
+		if err = e.notify(ctx, ch); err != nil {
+			return nil, err
+		}
+		if err = e.State.PutTransaction(ba.Slug, t); err != nil {
+			return nil, err
+		}
+		if ch.Kind == Created {
+			res.Created++
+		} else {
+			res.Updated++
+		}
+	}
+
+	// only a full listing allows to detect the transactions that disappeared
+	if full {
+		ids, err := e.State.TransactionIDs(ba.Slug)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			t, err := e.State.Transaction(id)
+			if err != nil {
+				return nil, err
+			}
+			if err = e.notify(ctx, Change{Kind: Removed, Account: ba.Slug, Transaction: t, Previous: t}); err != nil {
+				return nil, err
+			}
+			if err = e.State.DeleteTransaction(ba.Slug, id); err != nil {
+				return nil, err
+			}
+			res.Removed++
+		}
+	}
+
+	if err = e.State.SetWatermark(ba.Slug, res.Watermark); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (e *Engine) notify(ctx context.Context, ch Change) error {
+	if e.OnChange == nil {
+		return nil
+	}
+	return e.OnChange(ctx, ch)
+}
+
+// Equal reports whether a and b hold the same data.
+func Equal(a, b *qonto.Transaction) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,101 @@
+package sync_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/sync"
+)
+
+// fakeLister returns the transactions updated after options.UpdatedAtFrom.
+type fakeLister struct {
+	transactions []*qonto.Transaction
+	lastOptions  *qonto.GetTransactionOptions
+}
+
+func (l *fakeLister) GetAllTransactionsContext(ctx context.Context, bankAccountID, IBAN string, options *qonto.GetTransactionOptions) ([]*qonto.Transaction, error) {
+	l.lastOptions = options
+	var res []*qonto.Transaction
+	for _, t := range l.transactions {
+		if options.UpdatedAtFrom == nil || !t.UpdatedAt.Before(*options.UpdatedAtFrom) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+func newTransaction(id string, status qonto.TransactionStatus, updatedAt time.Time) *qonto.Transaction {
+	return &qonto.Transaction{ID: id, Status: status, UpdatedAt: &updatedAt}
+}
+
+func TestEngine_Sync(t *testing.T) {
+	ba := &qonto.BankAccount{Slug: "account", IBAN: "FR76"}
+	t0 := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := &fakeLister{transactions: []*qonto.Transaction{
+		newTransaction("t1", qonto.TransactionStatusCompleted, t0),
+		newTransaction("t2", qonto.TransactionStatusPending, t0.Add(time.Hour)),
+	}}
+
+	var changes []sync.Change
+	e := sync.NewEngine(l, sync.NewMemoryState())
+	e.OnChange = func(ctx context.Context, ch sync.Change) error {
+		changes = append(changes, ch)
+		return nil
+	}
+
+	// first run: everything is new
+	res, err := e.Sync(context.Background(), ba)
+	if err != nil {
+		t.Fatalf("e.Sync() failed: %v", err)
+	}
+	if res.Created != 2 || res.Updated != 0 {
+		t.Errorf("res == %+v; want 2 created", res)
+	}
+	if !res.Watermark.Equal(t0.Add(time.Hour)) {
+		t.Errorf("res.Watermark == %v; want %v", res.Watermark, t0.Add(time.Hour))
+	}
+	if l.lastOptions.UpdatedAtFrom != nil {
+		t.Errorf("first sync should not filter on updated_at")
+	}
+
+	// second run: t2 was settled, and t1 is returned again because of the overlap
+	l.transactions[1] = newTransaction("t2", qonto.TransactionStatusCompleted, t0.Add(time.Hour+time.Minute))
+	changes = nil
+	res, err = e.Sync(context.Background(), ba)
+	if err != nil {
+		t.Fatalf("e.Sync() failed: %v", err)
+	}
+	if want := t0.Add(time.Hour - sync.DefaultOverlap); !l.lastOptions.UpdatedAtFrom.Equal(want) {
+		t.Errorf("UpdatedAtFrom == %v; want %v", l.lastOptions.UpdatedAtFrom, want)
+	}
+	if res.Created != 0 || res.Updated != 1 {
+		t.Errorf("res == %+v; want 1 updated", res)
+	}
+	if len(changes) != 1 || changes[0].Kind != sync.Updated || changes[0].Previous.Status != qonto.TransactionStatusPending {
+		t.Errorf("changes == %+v; want a single update from pending", changes)
+	}
+}
+
+func TestEngine_FullSync(t *testing.T) {
+	ba := &qonto.BankAccount{Slug: "account", IBAN: "FR76"}
+	t0 := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	state := sync.NewMemoryState()
+	_ = state.PutTransaction(ba.Slug, newTransaction("gone", qonto.TransactionStatusPending, t0))
+	l := &fakeLister{transactions: []*qonto.Transaction{
+		newTransaction("t1", qonto.TransactionStatusCompleted, t0),
+	}}
+
+	e := sync.NewEngine(l, state)
+	res, err := e.FullSync(context.Background(), ba)
+	if err != nil {
+		t.Fatalf("e.FullSync() failed: %v", err)
+	}
+	if res.Created != 1 || res.Removed != 1 {
+		t.Errorf("res == %+v; want 1 created and 1 removed", res)
+	}
+	if tr, _ := state.Transaction("gone"); tr != nil {
+		t.Errorf("removed transaction is still in the state")
+	}
+}
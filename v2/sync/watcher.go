@@ -0,0 +1,222 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// DefaultInterval is the default value for Watcher.Interval.
+const DefaultInterval = time.Minute
+
+// TransactionPager fetches a single page of transactions.
+// It is implemented by *qonto.Client.
+type TransactionPager interface {
+	GetTransactionsContext(ctx context.Context, bankAccountID, IBAN string, options *qonto.GetTransactionOptions) (*qonto.TransactionsPage, error)
+}
+
+// Event is implemented by all the events sent by a Watcher:
+// TransactionCreated, TransactionSettled, TransactionDeclined, TransactionReversed and AttachmentAdded.
+type Event interface {
+	isEvent()
+}
+
+// TransactionCreated is sent the first time a transaction is seen.
+type TransactionCreated struct {
+	Account     string
+	Transaction *qonto.Transaction
+}
+
+// TransactionSettled is sent when a known transaction becomes "completed".
+type TransactionSettled struct {
+	Account     string
+	Transaction *qonto.Transaction
+	Previous    *qonto.Transaction
+}
+
+// TransactionDeclined is sent when a known transaction becomes "declined".
+type TransactionDeclined struct {
+	Account     string
+	Transaction *qonto.Transaction
+	Previous    *qonto.Transaction
+}
+
+// TransactionReversed is sent when a known transaction becomes "reversed".
+type TransactionReversed struct {
+	Account     string
+	Transaction *qonto.Transaction
+	Previous    *qonto.Transaction
+}
+
+// AttachmentAdded is sent for each new attachment of a known transaction.
+type AttachmentAdded struct {
+	Account      string
+	Transaction  *qonto.Transaction
+	AttachmentID string
+}
+
+func (TransactionCreated) isEvent()  {}
+func (TransactionSettled) isEvent()  {}
+func (TransactionDeclined) isEvent() {}
+func (TransactionReversed) isEvent() {}
+func (AttachmentAdded) isEvent()     {}
+
+// Watcher periodically polls Qonto for the transactions of a bank account, and sends
+// an Event for every change it detects.
+//
+// The cursor (the "updated_at" watermark) is saved into the State after every page, once
+// all the events of the page have been delivered, so a restarted Watcher resumes where
+// the previous one stopped.
+type Watcher struct {
+	Client  TransactionPager
+	State   State
+	Account *qonto.BankAccount
+	// Interval is the delay between two polls (NewWatcher sets it to DefaultInterval)
+	Interval time.Duration
+	// Overlap is substracted from the cursor before each poll (NewWatcher sets it to DefaultOverlap)
+	Overlap time.Duration
+	// PerPage is the page size used when listing transactions (defaults to the API default)
+	PerPage int
+	// OnError is called when a poll fails; when nil, the first error stops Run
+	OnError func(err error)
+}
+
+// NewWatcher creates a Watcher for the bank account ba, with the default options.
+func NewWatcher(client TransactionPager, state State, ba *qonto.BankAccount) *Watcher {
+	return &Watcher{
+		Client:   client,
+		State:    state,
+		Account:  ba,
+		Interval: DefaultInterval,
+		Overlap:  DefaultOverlap,
+	}
+}
+
+// Run polls Qonto until ctx is canceled, sending the events on ch.
+//
+// Sending blocks until the consumer reads the event, so a slow consumer slows down the
+// polling instead of accumulating events in memory. Run closes ch before returning,
+// and returns ctx.Err() after a graceful shutdown.
+func (w *Watcher) Run(ctx context.Context, ch chan<- Event) error {
+	defer close(ch)
+	if w.Account == nil {
+		return qonto.ErrBankAccountNeeded
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx, ch); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError == nil {
+				return err
+			}
+			w.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches, page by page, the transactions updated since the cursor and sends
+// the corresponding events on ch.
+func (w *Watcher) Poll(ctx context.Context, ch chan<- Event) error {
+	if w.Account == nil {
+		return qonto.ErrBankAccountNeeded
+	}
+	account := w.Account.Slug
+	cursor, err := w.State.Watermark(account)
+	if err != nil {
+		return err
+	}
+
+	options := &qonto.GetTransactionOptions{SortBy: &SortByUpdatedAt}
+	if !cursor.IsZero() {
+		from := cursor.Add(-w.Overlap)
+		options.UpdatedAtFrom = &from
+	}
+	if w.PerPage > 0 {
+		options.PerPage = &w.PerPage
+	}
+
+	currentPage := 1
+	for {
+		options.CurrentPage = &currentPage
+		page, err := w.Client.GetTransactionsContext(ctx, w.Account.Slug, w.Account.IBAN, options)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range page.Transactions {
+			previous, err := w.State.Transaction(t.ID)
+			if err != nil {
+				return err
+			}
+			if previous != nil && Equal(previous, t) {
+				continue
+			}
+			for _, e := range events(account, previous, t) {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err = w.State.PutTransaction(account, t); err != nil {
+				return err
+			}
+			if t.UpdatedAt != nil && t.UpdatedAt.After(cursor) {
+				cursor = *t.UpdatedAt
+			}
+		}
+
+		// the whole page was delivered: we can move the cursor
+		if err = w.State.SetWatermark(account, cursor); err != nil {
+			return err
+		}
+		if page.Meta.NextPage == nil {
+			return nil
+		}
+		currentPage = *page.Meta.NextPage
+	}
+}
+
+// events lists the events describing the transition from previous (nil for new transactions) to t.
+func events(account string, previous, t *qonto.Transaction) []Event {
+	if previous == nil {
+		return []Event{TransactionCreated{Account: account, Transaction: t}}
+	}
+
+	var res []Event
+	if previous.Status != t.Status {
+		switch t.Status {
+		case qonto.TransactionStatusCompleted:
+			res = append(res, TransactionSettled{Account: account, Transaction: t, Previous: previous})
+		case qonto.TransactionStatusDeclined:
+			res = append(res, TransactionDeclined{Account: account, Transaction: t, Previous: previous})
+		case qonto.TransactionStatusReversed:
+			res = append(res, TransactionReversed{Account: account, Transaction: t, Previous: previous})
+		}
+	}
+	known := make(map[string]bool, len(previous.AttachmentIDs))
+	for _, id := range previous.AttachmentIDs {
+		known[id] = true
+	}
+	for _, id := range t.AttachmentIDs {
+		if !known[id] {
+			res = append(res, AttachmentAdded{Account: account, Transaction: t, AttachmentID: id})
+		}
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+package sync_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/sync"
+)
+
+// fakePager returns the transactions one per page.
+type fakePager struct {
+	transactions []*qonto.Transaction
+}
+
+func (p *fakePager) GetTransactionsContext(ctx context.Context, bankAccountID, IBAN string, options *qonto.GetTransactionOptions) (*qonto.TransactionsPage, error) {
+	page := &qonto.TransactionsPage{}
+	i := *options.CurrentPage - 1
+	if i < len(p.transactions) {
+		page.Transactions = p.transactions[i : i+1]
+	}
+	if i+1 < len(p.transactions) {
+		next := i + 2
+		page.Meta.NextPage = &next
+	}
+	return page, nil
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	ba := &qonto.BankAccount{Slug: "account", IBAN: "FR76"}
+	t0 := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := &fakePager{transactions: []*qonto.Transaction{
+		newTransaction("t1", qonto.TransactionStatusPending, t0),
+		newTransaction("t2", qonto.TransactionStatusPending, t0.Add(time.Minute)),
+	}}
+	state := sync.NewMemoryState()
+	w := sync.NewWatcher(p, state, ba)
+
+	ch := make(chan sync.Event, 10)
+	if err := w.Poll(context.Background(), ch); err != nil {
+		t.Fatalf("w.Poll() failed: %v", err)
+	}
+	if len(ch) != 2 {
+		t.Fatalf("len(ch) == %d; want %d", len(ch), 2)
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := (<-ch).(sync.TransactionCreated); !ok {
+			t.Errorf("event %d should be a TransactionCreated", i)
+		}
+	}
+	if cursor, _ := state.Watermark(ba.Slug); !cursor.Equal(t0.Add(time.Minute)) {
+		t.Errorf("cursor == %v; want %v", cursor, t0.Add(time.Minute))
+	}
+
+	// t1 is settled with an attachment, t2 is declined
+	settled := newTransaction("t1", qonto.TransactionStatusCompleted, t0.Add(2*time.Minute))
+	settled.AttachmentIDs = []string{"a1"}
+	p.transactions = []*qonto.Transaction{
+		settled,
+		newTransaction("t2", qonto.TransactionStatusDeclined, t0.Add(3*time.Minute)),
+	}
+	if err := w.Poll(context.Background(), ch); err != nil {
+		t.Fatalf("w.Poll() failed: %v", err)
+	}
+	if len(ch) != 3 {
+		t.Fatalf("len(ch) == %d; want %d", len(ch), 3)
+	}
+	if _, ok := (<-ch).(sync.TransactionSettled); !ok {
+		t.Errorf("first event should be a TransactionSettled")
+	}
+	if e, ok := (<-ch).(sync.AttachmentAdded); !ok || e.AttachmentID != "a1" {
+		t.Errorf("second event should be an AttachmentAdded for a1")
+	}
+	if _, ok := (<-ch).(sync.TransactionDeclined); !ok {
+		t.Errorf("third event should be a TransactionDeclined")
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	ba := &qonto.BankAccount{Slug: "account", IBAN: "FR76"}
+	p := &fakePager{transactions: []*qonto.Transaction{
+		newTransaction("t1", qonto.TransactionStatusPending, time.Now()),
+	}}
+	w := sync.NewWatcher(p, sync.NewMemoryState(), ba)
+	w.Interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan sync.Event)
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, ch) }()
+
+	if _, ok := (<-ch).(sync.TransactionCreated); !ok {
+		t.Errorf("first event should be a TransactionCreated")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("w.Run() == %v; want %v", err, context.Canceled)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf("channel should be closed after Run returns")
+	}
+}
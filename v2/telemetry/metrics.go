@@ -0,0 +1,190 @@
+/*
+Package telemetry provides ready-made qonto.Observer implementations: Metrics exposes
+Prometheus counters and histograms, and Tracer records OpenTelemetry-style spans.
+
+Example:
+
+	m := telemetry.NewMetrics()
+	c.Observer = qonto.Observers(m, telemetry.NewTracer(exporter))
+	http.Handle("/metrics", m)
+*/
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// DefaultBuckets are the upper bounds of the request duration histogram, in seconds.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// labels identify a series.
+type labels struct {
+	organization, endpoint, method, status string
+}
+
+// histogram counts the observations by bucket.
+type histogram struct {
+	counts []uint64 // one per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// Metrics is a qonto.Observer counting the requests, and an http.Handler serving the
+// metrics in the Prometheus text format:
+//
+//	qonto_requests_total{organization,endpoint,method,status}
+//	qonto_request_duration_seconds{organization,endpoint,method} (histogram)
+//	qonto_response_bytes_total{organization,endpoint,method}
+//	qonto_request_retries_total{organization,endpoint,method}
+//	qonto_cache_hits_total{organization,endpoint,method}
+//	qonto_cache_revalidations_total{organization,endpoint,method}
+//
+// The status is "error" when the API could not be reached.
+type Metrics struct {
+	Buckets []float64 // NewMetrics sets it to DefaultBuckets
+
+	mu            sync.Mutex
+	requests      map[labels]uint64
+	durations     map[labels]*histogram
+	bytes         map[labels]uint64
+	retries       map[labels]uint64
+	cacheHits     map[labels]uint64
+	revalidations map[labels]uint64
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Buckets:       DefaultBuckets,
+		requests:      make(map[labels]uint64),
+		durations:     make(map[labels]*histogram),
+		bytes:         make(map[labels]uint64),
+		retries:       make(map[labels]uint64),
+		cacheHits:     make(map[labels]uint64),
+		revalidations: make(map[labels]uint64),
+	}
+}
+
+// RequestStarted implements qonto.Observer.
+func (m *Metrics) RequestStarted(ctx context.Context, info *qonto.RequestInfo) context.Context {
+	return ctx
+}
+
+// RequestFinished implements qonto.Observer.
+func (m *Metrics) RequestFinished(ctx context.Context, info *qonto.RequestInfo, result *qonto.RequestResult) {
+	l := labels{organization: info.Organization, endpoint: info.Endpoint, method: info.Method}
+	status := "error"
+	if result.StatusCode > 0 {
+		status = strconv.Itoa(result.StatusCode)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[labels{l.organization, l.endpoint, l.method, status}]++
+	m.bytes[l] += uint64(result.Bytes)
+	if result.Retries > 0 {
+		m.retries[l] += uint64(result.Retries)
+	}
+	if result.CacheHit {
+		m.cacheHits[l]++
+	}
+	if result.Revalidated {
+		m.revalidations[l]++
+	}
+	h := m.durations[l]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.Buckets))}
+		m.durations[l] = h
+	}
+	seconds := result.Duration.Seconds()
+	for i, bound := range m.Buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// ServeHTTP writes the metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(w)
+}
+
+// Write writes the metrics in the Prometheus text format.
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	writeCounter(&b, "qonto_requests_total", "Number of requests sent to the Qonto API.", m.requests)
+	fmt.Fprintln(&b, "# HELP qonto_request_duration_seconds Duration of the requests sent to the Qonto API.")
+	fmt.Fprintln(&b, "# TYPE qonto_request_duration_seconds histogram")
+	durations := make([]labels, 0, len(m.durations))
+	for l := range m.durations {
+		durations = append(durations, l)
+	}
+	for _, l := range sortLabels(durations) {
+		h := m.durations[l]
+		var cumulative uint64
+		for i, bound := range m.Buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "qonto_request_duration_seconds_bucket{%s,le=%q} %d\n", l, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "qonto_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, h.count)
+		fmt.Fprintf(&b, "qonto_request_duration_seconds_sum{%s} %s\n", l, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "qonto_request_duration_seconds_count{%s} %d\n", l, h.count)
+	}
+	writeCounter(&b, "qonto_response_bytes_total", "Size of the response bodies read from the Qonto API.", m.bytes)
+	writeCounter(&b, "qonto_request_retries_total", "Number of requests sent again to the Qonto API.", m.retries)
+	writeCounter(&b, "qonto_cache_hits_total", "Number of requests served from the cache.", m.cacheHits)
+	writeCounter(&b, "qonto_cache_revalidations_total", "Number of cached responses revalidated by the Qonto API.", m.revalidations)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCounter(b *strings.Builder, name, help string, values map[labels]uint64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, l := range sortedLabels(values) {
+		fmt.Fprintf(b, "%s{%s} %d\n", name, l, values[l])
+	}
+}
+
+// String formats the labels for the Prometheus text format.
+func (l labels) String() string {
+	s := fmt.Sprintf("organization=%s,endpoint=%s,method=%s", quote(l.organization), quote(l.endpoint), quote(l.method))
+	if l.status != "" {
+		s += ",status=" + quote(l.status)
+	}
+	return s
+}
+
+// quote escapes a label value.
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+func sortedLabels(m map[labels]uint64) []labels {
+	res := make([]labels, 0, len(m))
+	for l := range m {
+		res = append(res, l)
+	}
+	return sortLabels(res)
+}
+
+func sortLabels(ls []labels) []labels {
+	sort.Slice(ls, func(i, j int) bool { return ls[i].String() < ls[j].String() })
+	return ls
+}
@@ -0,0 +1,139 @@
+package telemetry_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/telemetry"
+)
+
+func newServer(traceparents *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if traceparents != nil {
+			*traceparents = append(*traceparents, r.Header.Get("traceparent"))
+		}
+		if strings.HasPrefix(r.URL.Path, "/attachments/") {
+			http.Error(w, `{"errors":[{"code":"not_found"}]}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"organization":{"slug":"acme","bank_accounts":[]}}`)
+	}))
+}
+
+func TestMetrics(t *testing.T) {
+	srv := newServer(nil)
+	defer srv.Close()
+
+	m := telemetry.NewMetrics()
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Observer = m
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrganization(); err != nil {
+			t.Fatalf("c.GetOrganization() failed: %v", err)
+		}
+	}
+	if _, err := c.GetAttachment("abc-123"); err == nil {
+		t.Fatal("c.GetAttachment() did not fail")
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`qonto_requests_total{organization="acme",endpoint="/organizations/{id}",method="GET",status="200"} 2`,
+		`qonto_requests_total{organization="acme",endpoint="/attachments/{id}",method="GET",status="404"} 1`,
+		`qonto_request_duration_seconds_count{organization="acme",endpoint="/organizations/{id}",method="GET"} 2`,
+		`qonto_request_duration_seconds_bucket{organization="acme",endpoint="/organizations/{id}",method="GET",le="+Inf"} 2`,
+		`# TYPE qonto_cache_hits_total counter`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "abc-123") {
+		t.Errorf("metrics contain an identifier:\n%s", body)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	var traceparents []string
+	srv := newServer(&traceparents)
+	defer srv.Close()
+
+	recorder := &telemetry.SpanRecorder{}
+	tracer := telemetry.NewTracer(recorder)
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", srv.URL, nil)
+	c.Observer = tracer
+	c.Middlewares = []qonto.Middleware{telemetry.Propagate()}
+
+	ctx, parent := tracer.Start(context.Background(), "sync")
+	if _, err := c.GetOrganizationContext(ctx); err != nil {
+		t.Fatalf("c.GetOrganizationContext() failed: %v", err)
+	}
+	if _, err := c.GetAttachmentContext(ctx, "abc-123"); err == nil {
+		t.Fatal("c.GetAttachmentContext() did not fail")
+	}
+	tracer.End(parent)
+
+	spans := recorder.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("len(spans) == %d; want 3", len(spans))
+	}
+	org, attachment := spans[0], spans[1]
+	if org.Name != "GET /organizations/{id}" || org.Status != telemetry.SpanStatusOK || org.Attributes["http.response.status_code"] != 200 {
+		t.Errorf("org == %+v", org)
+	}
+	if attachment.Status != telemetry.SpanStatusError || attachment.Attributes["qonto.organization"] != "acme" {
+		t.Errorf("attachment == %+v", attachment)
+	}
+	for i, s := range spans[:2] {
+		if s.TraceID != parent.TraceID || s.ParentSpanID != parent.SpanID {
+			t.Errorf("spans[%d] is not a child of the parent span", i)
+		}
+		if want := fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID); traceparents[i] != want {
+			t.Errorf("traceparents[%d] == %q; want %q", i, traceparents[i], want)
+		}
+	}
+}
+
+func TestTracer_TransportFailure(t *testing.T) {
+	srv := newServer(nil)
+	closed := srv.URL
+	srv.Close()
+
+	recorder := &telemetry.SpanRecorder{}
+	c := qonto.NewClient("acme", "secret", nil)
+	c.Environment = qonto.CustomEnvironment("local", closed, nil)
+	c.Observer = telemetry.NewTracer(recorder)
+
+	if _, err := c.GetTransactions("acme-account", "FR7630001007941234567890185", nil); err == nil {
+		t.Fatal("c.GetTransactions() did not fail")
+	}
+	a := &qonto.Attachment{URL: closed + "/file.pdf?X-Amz-Credential=AKIA&X-Amz-Signature=deadbeefsecret"}
+	if _, err := c.DownloadAttachment(a); err == nil {
+		t.Fatal("c.DownloadAttachment() did not fail")
+	}
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("len(spans) == %d; want 2", len(spans))
+	}
+	for _, s := range spans {
+		if s.Status != telemetry.SpanStatusError || s.StatusMessage == "" {
+			t.Errorf("span %s == %+v; want an error", s.Name, s)
+		}
+		for _, secret := range []string{"FR7630001007941234567890185", "deadbeefsecret", "AKIA"} {
+			if strings.Contains(s.StatusMessage, secret) {
+				t.Errorf("span %s StatusMessage contains %q: %s", s.Name, secret, s.StatusMessage)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// SpanStatus is the status of a span, as defined by OpenTelemetry.
+type SpanStatus string
+
+const (
+	// SpanStatusUnset is the default status
+	SpanStatusUnset SpanStatus = "unset"
+	// SpanStatusOK marks successful operations
+	SpanStatusOK SpanStatus = "ok"
+	// SpanStatusError marks failed operations
+	SpanStatusError SpanStatus = "error"
+)
+
+// Span is an operation, with the OpenTelemetry semantics.
+type Span struct {
+	TraceID       string // 32 hex characters
+	SpanID        string // 16 hex characters
+	ParentSpanID  string // empty for root spans
+	Name          string
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	Status        SpanStatus
+	StatusMessage string
+}
+
+// SpanExporter receives the ended spans.
+type SpanExporter interface {
+	ExportSpan(s *Span)
+}
+
+// SpanRecorder is a SpanExporter keeping the spans in memory.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// ExportSpan records s.
+func (r *SpanRecorder) ExportSpan(s *Span) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+// Spans returns the recorded spans, in the order they ended.
+func (r *SpanRecorder) Spans() []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Span(nil), r.spans...)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx holding s, the parent of the next spans.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the current span of ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Tracer is a qonto.Observer creating a client span for each request, as a child of the
+// span of the request context.
+type Tracer struct {
+	Exporter SpanExporter
+}
+
+// NewTracer returns a Tracer sending the spans to exporter.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+// Start creates a span, child of the span of ctx, and returns a context holding it.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	s := &Span{
+		SpanID:     randomHex(8),
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: make(map[string]interface{}),
+		Status:     SpanStatusUnset,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.TraceID, s.ParentSpanID = parent.TraceID, parent.SpanID
+	} else {
+		s.TraceID = randomHex(16)
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// End ends s, and exports it.
+func (t *Tracer) End(s *Span) {
+	s.End = time.Now()
+	if t.Exporter != nil {
+		t.Exporter.ExportSpan(s)
+	}
+}
+
+// RequestStarted implements qonto.Observer.
+func (t *Tracer) RequestStarted(ctx context.Context, info *qonto.RequestInfo) context.Context {
+	ctx, s := t.Start(ctx, info.Method+" "+info.Endpoint)
+	s.Attributes["http.request.method"] = info.Method
+	s.Attributes["url.template"] = info.Endpoint
+	s.Attributes["qonto.organization"] = info.Organization
+	return ctx
+}
+
+// RequestFinished implements qonto.Observer.
+func (t *Tracer) RequestFinished(ctx context.Context, info *qonto.RequestInfo, result *qonto.RequestResult) {
+	s := SpanFromContext(ctx)
+	if s == nil {
+		return
+	}
+	if result.StatusCode > 0 {
+		s.Attributes["http.response.status_code"] = result.StatusCode
+	}
+	s.Attributes["http.response.body.size"] = result.Bytes
+	if result.Retries > 0 {
+		s.Attributes["http.request.resend_count"] = result.Retries
+	}
+	if result.CacheHit {
+		s.Attributes["qonto.cache_hit"] = true
+	}
+	if result.Revalidated {
+		s.Attributes["qonto.cache_revalidated"] = true
+	}
+	switch {
+	case result.Err != nil:
+		s.Status, s.StatusMessage = SpanStatusError, qonto.RedactError(result.Err) // ⬅︎ the URLs hold IBANs and signatures
+	case result.StatusCode >= 400:
+		s.Status = SpanStatusError
+	default:
+		s.Status = SpanStatusOK
+	}
+	t.End(s)
+}
+
+// Propagate is a qonto.Middleware adding the W3C "traceparent" header of the current span to the requests.
+func Propagate() qonto.Middleware {
+	return func(next qonto.Doer) qonto.Doer {
+		return qonto.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if s := SpanFromContext(req.Context()); s != nil {
+				req.Header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID))
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
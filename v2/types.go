@@ -81,6 +81,7 @@ type Transaction struct {
 	LocalCurrency      string            `json:"local_currency"`
 	SettledAt          *time.Time        `json:"settled_at,omitempty"`
 	EmittedAt          time.Time         `json:"emitted_at"`
+	UpdatedAt          *time.Time        `json:"updated_at,omitempty"`
 	Status             TransactionStatus `json:"status"`
 	Note               *string           `json:"note,omitempty"`
 	Label              *string           `json:"label,omitempty"`
@@ -105,6 +105,12 @@ func TestTransaction_(t *testing.T) {
 	if tr.EmittedAt != emittedTime {
 		t.Errorf("o.EmittedAt == %q; want %q", tr.EmittedAt, emittedTime)
 	}
+	updatedTime, _ := time.Parse(time.RFC3339, "2018-10-02T09:12:45.000Z")
+	if tr.UpdatedAt == nil {
+		t.Errorf("o.UpdatedAt == nil; want %q", updatedTime)
+	} else if *tr.UpdatedAt != updatedTime {
+		t.Errorf("o.UpdatedAt == %q; want %q", tr.UpdatedAt, updatedTime)
+	}
 	if tr.Status != qonto.TransactionStatusCompleted {
 		t.Errorf("o.Status == %q; want %q", tr.Status, qonto.TransactionStatusCompleted)
 	}
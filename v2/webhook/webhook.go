@@ -0,0 +1,331 @@
+/*
+Package webhook receives the webhooks sent by Qonto.
+
+The Handler is an http.Handler that checks the signature of each request with the
+secret shared with Qonto, rejects stale or replayed deliveries, decodes the payload and
+dispatches it to the function registered for its event type. Redeliveries of an event already
+processed are acknowledged without calling the function again, so the endpoint is idempotent, and
+the ones received while the event is still processed are answered with a 409 status, so that
+Qonto retries them later.
+
+The signature is expected in the X-Qonto-Signature header, formatted as
+
+	t=<unix timestamp>,v1=<hex encoded HMAC-SHA256 of "<timestamp>.<body>">
+
+Example:
+
+	h := webhook.NewHandler("webhook-secret")
+	h.Handle(webhook.TransactionCreated, func(ctx context.Context, e *webhook.Event) error {
+		fmt.Printf("new transaction %s\n", e.Transaction.ID)
+		return nil
+	})
+	http.Handle("/qonto/webhook", h)
+
+In tests, a Signer builds requests accepted by the Handler:
+
+	s := &webhook.Signer{Secret: []byte("webhook-secret")}
+	req, _ := s.NewRequest(srv.URL, &webhook.Event{ID: "evt-1", Type: webhook.TransactionCreated, Transaction: t})
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+)
+
+// SignatureHeader is the name of the header holding the signature.
+const SignatureHeader = "X-Qonto-Signature"
+
+// DefaultTolerance is the default value for Handler.Tolerance.
+const DefaultTolerance = 5 * time.Minute
+
+// maxBodySize limits the size of the accepted payloads.
+const maxBodySize = 1 << 20
+
+// ErrMissingSignature is returned when the signature header is missing or malformed.
+var ErrMissingSignature = errors.New("Missing webhook signature")
+
+// ErrInvalidSignature is returned when the signature does not match the payload.
+var ErrInvalidSignature = errors.New("Invalid webhook signature")
+
+// ErrStaleSignature is returned when the signature timestamp is outside of the tolerance window.
+var ErrStaleSignature = errors.New("Webhook signature timestamp is out of tolerance")
+
+// EventType identifies the kind of event sent by Qonto.
+type EventType string
+
+const (
+	// TransactionCreated is sent when a new transaction is created.
+	TransactionCreated EventType = "transaction.created"
+	// TransactionUpdated is sent when a transaction changes (status, note, labels etc.).
+	TransactionUpdated EventType = "transaction.updated"
+)
+
+// Event is the payload of a webhook.
+type Event struct {
+	ID          string             `json:"id"`
+	Type        EventType          `json:"type"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Transaction *qonto.Transaction `json:"transaction,omitempty"`
+}
+
+// EventHandler handles a verified Event. Returning an error makes the Handler respond
+// with a 500 status, so that Qonto retries the delivery.
+type EventHandler func(ctx context.Context, e *Event) error
+
+// Handler is an http.Handler receiving Qonto webhooks.
+type Handler struct {
+	secret []byte
+	// Tolerance is the maximum accepted age of a signature, it also bounds the memory of the
+	// processed deliveries (NewHandler sets it to DefaultTolerance, and it defaults to
+	// DefaultTolerance when not positive)
+	Tolerance time.Duration
+	// Now returns the current time (defaults to time.Now)
+	Now func() time.Time
+
+	mu         sync.Mutex
+	handlers   map[EventType]EventHandler
+	processing map[string]bool      // deliveries being processed, by key
+	processed  map[string]time.Time // replay protection: delivery key ➡︎ expiry
+	expiries   []delivery           // the processed deliveries, by expiry
+}
+
+// delivery is a processed delivery, remembered until it expires.
+type delivery struct {
+	key     string
+	expires time.Time
+}
+
+// NewHandler creates a Handler verifying the signatures with the provided secret.
+func NewHandler(secret string) *Handler {
+	return &Handler{
+		secret:     []byte(secret),
+		Tolerance:  DefaultTolerance,
+		handlers:   make(map[EventType]EventHandler),
+		processing: make(map[string]bool),
+		processed:  make(map[string]time.Time),
+	}
+}
+
+// Handle registers the function called for the events of type t.
+// Events without a registered function are acknowledged and ignored.
+func (h *Handler) Handle(t EventType, fn EventHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[t] = fn
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err = h.Verify(r.Header.Get(SignatureHeader), body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var e Event
+	if err = json.Unmarshal(body, &e); err != nil {
+		http.Error(w, "could not decode payload", http.StatusBadRequest)
+		return
+	}
+
+	// replays are detected only once the signature is known to be valid, and acknowledged:
+	// Qonto redelivers the events when an acknowledgement is lost
+	key := e.ID
+	if key == "" {
+		key = r.Header.Get(SignatureHeader)
+	}
+	switch h.begin(key) {
+	case stateProcessed:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case stateProcessing:
+		// the outcome is not known yet: the delivery must be retried later
+		http.Error(w, "delivery in progress", http.StatusConflict)
+		return
+	}
+
+	h.mu.Lock()
+	fn := h.handlers[e.Type]
+	h.mu.Unlock()
+	if fn != nil {
+		if err = fn(r.Context(), &e); err != nil {
+			h.finish(key, false)
+			http.Error(w, "handler failed", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.finish(key, true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Verify checks the signature header against the body, and returns the signature time.
+func (h *Handler) Verify(header string, body []byte) (time.Time, error) {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	if ts == "" || len(signatures) == 0 {
+		return time.Time{}, ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrMissingSignature
+	}
+	t := time.Unix(unix, 0)
+
+	// check the timestamp
+	age := h.now().Sub(t)
+	if age < 0 {
+		age = -age
+	}
+	if age > h.tolerance() {
+		return t, ErrStaleSignature
+	}
+
+	// and the signature itself (several signatures are allowed during secret rotations)
+	expected := computeSignature(h.secret, ts, body)
+	for _, s := range signatures {
+		sig, err := hex.DecodeString(s)
+		if err == nil && hmac.Equal(sig, expected) {
+			return t, nil
+		}
+	}
+	return t, ErrInvalidSignature
+}
+
+// deliveryState is the state of a delivery key.
+type deliveryState int
+
+const (
+	stateNew deliveryState = iota
+	stateProcessing
+	stateProcessed
+)
+
+// begin returns the state of the delivery key, and marks new keys as being processed.
+func (h *Handler) begin(key string) deliveryState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// drop the keys that can no longer be replayed (their signature would be stale), the
+	// expiries are in processing order so only the expired ones are visited
+	now := h.now()
+	n := 0
+	for ; n < len(h.expiries) && now.After(h.expiries[n].expires); n++ {
+		if d := h.expiries[n]; h.processed[d.key].Equal(d.expires) {
+			delete(h.processed, d.key)
+		}
+	}
+	h.expiries = h.expiries[n:]
+
+	if _, ok := h.processed[key]; ok {
+		return stateProcessed
+	}
+	if h.processing[key] {
+		return stateProcessing
+	}
+	h.processing[key] = true
+	return stateNew
+}
+
+// finish ends the processing of a delivery key. Only the successful deliveries are
+// remembered, so that Qonto can retry the ones that failed.
+func (h *Handler) finish(key string, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.processing, key)
+	if !ok {
+		return
+	}
+	// a signature accepted until now can be replayed during another tolerance window
+	expires := h.now().Add(2 * h.tolerance())
+	h.processed[key] = expires
+	h.expiries = append(h.expiries, delivery{key, expires})
+}
+
+func (h *Handler) tolerance() time.Duration {
+	if h.Tolerance <= 0 {
+		return DefaultTolerance
+	}
+	return h.Tolerance
+}
+
+func (h *Handler) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
+
+// Signer signs payloads the way Qonto does, to test webhook receivers.
+type Signer struct {
+	Secret []byte
+	// Now returns the signature time (defaults to time.Now)
+	Now func() time.Time
+}
+
+// Sign returns the value of the signature header for body.
+func (s *Signer) Sign(body []byte) string {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	ts := strconv.FormatInt(now().Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(computeSignature(s.Secret, ts, body)))
+}
+
+// NewRequest creates a signed POST request delivering e to url.
+func (s *Signer) NewRequest(url string, e *Event) (*http.Request, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, s.Sign(body))
+	return req, nil
+}
+
+func computeSignature(secret []byte, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
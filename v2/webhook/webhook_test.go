@@ -0,0 +1,191 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ushu/qonto-go/v2"
+	"github.com/ushu/qonto-go/v2/webhook"
+)
+
+func TestHandler(t *testing.T) {
+	var received *webhook.Event
+	h := webhook.NewHandler("secret")
+	h.Handle(webhook.TransactionCreated, func(ctx context.Context, e *webhook.Event) error {
+		received = e
+		return nil
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	s := &webhook.Signer{Secret: []byte("secret")}
+	e := &webhook.Event{
+		ID:          "evt-1",
+		Type:        webhook.TransactionCreated,
+		Transaction: &qonto.Transaction{ID: "transaction-1", AmountCents: 12042},
+	}
+
+	req, _ := s.NewRequest(srv.URL, e)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("res.StatusCode == %d; want %d", res.StatusCode, http.StatusNoContent)
+	}
+	if received == nil || received.Transaction.ID != "transaction-1" || received.Transaction.AmountCents != 12042 {
+		t.Errorf("received == %+v; want transaction-1", received)
+	}
+
+	// the same delivery is acknowledged, without calling the handler again
+	received = nil
+	req, _ = s.NewRequest(srv.URL, e)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("res.StatusCode == %d; want %d", res.StatusCode, http.StatusNoContent)
+	}
+	if received != nil {
+		t.Errorf("the handler was called again for a redelivery")
+	}
+}
+
+func TestHandler_Verify(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	h := webhook.NewHandler("secret")
+	h.Now = func() time.Time { return now }
+	body := []byte(`{"id":"evt-1"}`)
+
+	tests := []struct {
+		name   string
+		signer *webhook.Signer
+		header string
+		want   error
+	}{
+		{"valid", &webhook.Signer{Secret: []byte("secret"), Now: h.Now}, "", nil},
+		{"wrong secret", &webhook.Signer{Secret: []byte("other"), Now: h.Now}, "", webhook.ErrInvalidSignature},
+		{"stale", &webhook.Signer{Secret: []byte("secret"), Now: func() time.Time { return now.Add(-time.Hour) }}, "", webhook.ErrStaleSignature},
+		{"missing", nil, "", webhook.ErrMissingSignature},
+		{"malformed", nil, "v1=abcd", webhook.ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		header := tt.header
+		if tt.signer != nil {
+			header = tt.signer.Sign(body)
+		}
+		if _, err := h.Verify(header, body); err != tt.want {
+			t.Errorf("%s: h.Verify() == %v; want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// the timestamp is always checked, so that the processed deliveries can be forgotten
+	h.Tolerance = 0
+	stale := &webhook.Signer{Secret: []byte("secret"), Now: func() time.Time { return now.Add(-time.Hour) }}
+	if _, err := h.Verify(stale.Sign(body), body); err != webhook.ErrStaleSignature {
+		t.Errorf("h.Verify() == %v with no Tolerance; want %v", err, webhook.ErrStaleSignature)
+	}
+}
+
+func TestHandler_Rejected(t *testing.T) {
+	h := webhook.NewHandler("secret")
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(webhook.SignatureHeader, "t=1,v1=00")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("rec.Code == %d; want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHandler_Concurrent(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	h := webhook.NewHandler("secret")
+	h.Handle(webhook.TransactionCreated, func(ctx context.Context, e *webhook.Event) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			return errors.New("failed")
+		}
+		return nil
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	s := &webhook.Signer{Secret: []byte("secret")}
+	e := &webhook.Event{ID: "evt-1", Type: webhook.TransactionCreated}
+	post := func() int {
+		req, _ := s.NewRequest(srv.URL, e)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("POST failed: %v", err)
+			return 0
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	first := make(chan int)
+	go func() { first <- post() }()
+	<-started
+
+	// the delivery is being processed: the redelivery must be retried later
+	if code := post(); code != http.StatusConflict {
+		t.Errorf("POST == %d while processing; want %d", code, http.StatusConflict)
+	}
+	close(release)
+	if code := <-first; code != http.StatusInternalServerError {
+		t.Errorf("POST == %d; want %d", code, http.StatusInternalServerError)
+	}
+
+	// the failed delivery is processed again, and then acknowledged
+	for i, want := range []int32{2, 2} {
+		if code := post(); code != http.StatusNoContent {
+			t.Errorf("POST #%d == %d; want %d", i+1, code, http.StatusNoContent)
+		}
+		if n := atomic.LoadInt32(&calls); n != want {
+			t.Errorf("calls == %d after POST #%d; want %d", n, i+1, want)
+		}
+	}
+}
+
+func TestHandler_Expiry(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	var calls int
+	h := webhook.NewHandler("secret")
+	h.Now = func() time.Time { return now }
+	h.Handle(webhook.TransactionCreated, func(ctx context.Context, e *webhook.Event) error {
+		calls++
+		return nil
+	})
+	s := &webhook.Signer{Secret: []byte("secret"), Now: func() time.Time { return now }}
+	e := &webhook.Event{ID: "evt-1", Type: webhook.TransactionCreated}
+	post := func() int {
+		req, _ := s.NewRequest("/", e)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	post()
+	now = now.Add(webhook.DefaultTolerance)
+	post()
+	if calls != 1 {
+		t.Errorf("calls == %d within the tolerance; want 1", calls)
+	}
+
+	// once expired, the delivery is forgotten (only a fresh signature is accepted)
+	now = now.Add(2*webhook.DefaultTolerance + time.Second)
+	if code := post(); code != http.StatusNoContent || calls != 2 {
+		t.Errorf("POST == %d, calls == %d after expiry; want %d, 2", code, calls, http.StatusNoContent)
+	}
+}